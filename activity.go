@@ -0,0 +1,78 @@
+package forges
+
+import (
+	"strings"
+	"time"
+)
+
+const week = 7 * 24 * time.Hour
+
+// weekStart returns the start of the week containing t, Sunday 00:00 UTC,
+// which is how GitHub's statistics API buckets commits.
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -int(day.Weekday()))
+}
+
+// activityBuilder accumulates commits into an Activity with one bucket per
+// week from since up to now.
+type activityBuilder struct {
+	activity   Activity
+	committers map[string]bool
+}
+
+func newActivityBuilder(since, now time.Time) *activityBuilder {
+	b := &activityBuilder{
+		activity:   Activity{Since: since},
+		committers: make(map[string]bool),
+	}
+	for w := weekStart(since); !w.After(now); w = w.Add(week) {
+		b.activity.Weeks = append(b.activity.Weeks, WeeklyCommits{Week: w})
+	}
+	return b
+}
+
+// noteLastCommit records t as the last commit date if it is the newest seen.
+func (b *activityBuilder) noteLastCommit(t time.Time) {
+	if t.After(b.activity.LastCommitAt) {
+		b.activity.LastCommitAt = t
+	}
+}
+
+// addCommit records a single commit by author at t. Commits before since
+// only count towards the last commit date.
+func (b *activityBuilder) addCommit(author string, t time.Time) {
+	b.noteLastCommit(t)
+	if t.Before(b.activity.Since) {
+		return
+	}
+	b.addWeek(t, 1)
+	b.addCommitter(author)
+}
+
+// addWeek adds commits to the bucket for the week containing t.
+func (b *activityBuilder) addWeek(t time.Time, commits int) {
+	if len(b.activity.Weeks) == 0 {
+		return
+	}
+	i := int(weekStart(t).Sub(b.activity.Weeks[0].Week) / week)
+	if i < 0 || i >= len(b.activity.Weeks) {
+		return
+	}
+	b.activity.Weeks[i].Commits += commits
+	b.activity.TotalCommits += commits
+}
+
+// addCommitter records a distinct commit author. Identities are compared
+// case-insensitively since emails and logins are case-insensitive.
+func (b *activityBuilder) addCommitter(author string) {
+	if author != "" {
+		b.committers[strings.ToLower(author)] = true
+	}
+}
+
+func (b *activityBuilder) result() *Activity {
+	b.activity.Committers = len(b.committers)
+	return &b.activity
+}
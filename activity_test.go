@@ -0,0 +1,55 @@
+package forges
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWeekStart(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"2024-06-12T15:04:05Z", "2024-06-09T00:00:00Z"}, // Wednesday
+		{"2024-06-09T00:00:00Z", "2024-06-09T00:00:00Z"}, // Sunday midnight
+		{"2024-06-08T23:59:59Z", "2024-06-02T00:00:00Z"}, // Saturday
+		{"2024-06-09T01:00:00+02:00", "2024-06-02T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		got := weekStart(parseTime(tt.in))
+		assertEqual(t, tt.in, tt.want, got.Format(time.RFC3339))
+	}
+}
+
+func TestActivityBuilder(t *testing.T) {
+	since := parseTime("2024-06-05T00:00:00Z") // Wednesday
+	now := parseTime("2024-06-20T12:00:00Z")
+	b := newActivityBuilder(since, now)
+
+	b.addCommit("alice@example.com", parseTime("2024-06-19T10:00:00Z"))
+	b.addCommit("Alice@example.com", parseTime("2024-06-10T10:00:00Z"))
+	b.addCommit("bob@example.com", parseTime("2024-06-06T10:00:00Z"))
+	b.addCommit("carol@example.com", parseTime("2024-06-03T10:00:00Z")) // before since
+
+	a := b.result()
+	if len(a.Weeks) != 3 {
+		t.Fatalf("expected 3 weeks, got %d", len(a.Weeks))
+	}
+	assertEqual(t, "Weeks[0].Week", "2024-06-02T00:00:00Z", a.Weeks[0].Week.Format(time.RFC3339))
+	assertEqualInt(t, "Weeks[0].Commits", 1, a.Weeks[0].Commits)
+	assertEqualInt(t, "Weeks[1].Commits", 1, a.Weeks[1].Commits)
+	assertEqualInt(t, "Weeks[2].Commits", 1, a.Weeks[2].Commits)
+	assertEqualInt(t, "TotalCommits", 3, a.TotalCommits)
+	assertEqualInt(t, "Committers", 2, a.Committers)
+	assertEqual(t, "LastCommitAt", "2024-06-19T10:00:00Z", a.LastCommitAt.Format(time.RFC3339))
+}
+
+func TestActivityBuilderOnlyOldCommits(t *testing.T) {
+	since := parseTime("2024-06-05T00:00:00Z")
+	b := newActivityBuilder(since, parseTime("2024-06-06T00:00:00Z"))
+	b.noteLastCommit(parseTime("2023-01-01T00:00:00Z"))
+
+	a := b.result()
+	assertEqualInt(t, "TotalCommits", 0, a.TotalCommits)
+	assertEqualInt(t, "Committers", 0, a.Committers)
+	assertEqual(t, "LastCommitAt", "2023-01-01T00:00:00Z", a.LastCommitAt.Format(time.RFC3339))
+}
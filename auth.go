@@ -0,0 +1,197 @@
+package forges
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthMode selects how credentials are presented to a forge.
+type AuthMode string
+
+const (
+	// AuthToken sends Token the way the forge expects API tokens: as a
+	// Bearer token on GitHub and Bitbucket, in the Private-Token header on
+	// GitLab and as "token <token>" on Gitea. It is the default.
+	AuthToken AuthMode = "token"
+	// AuthBearer sends Token as an OAuth Bearer token on any forge, as for
+	// Bitbucket workspace and repository access tokens or GitLab OAuth
+	// tokens.
+	AuthBearer AuthMode = "bearer"
+	// AuthBasic sends Username and Password with HTTP basic auth: a
+	// Bitbucket username and app password, an Atlassian account email and
+	// API token, or a Gitea username and password.
+	AuthBasic AuthMode = "basic"
+	// AuthJobToken sends Token as a GitLab CI job token.
+	AuthJobToken AuthMode = "job_token"
+	// AuthOAuth2ClientCredentials exchanges ClientID and ClientSecret for an
+	// access token with the OAuth2 client credentials grant, as for a
+	// Bitbucket OAuth consumer, and fetches a new one before it expires.
+	AuthOAuth2ClientCredentials AuthMode = "oauth2_client_credentials"
+)
+
+// bitbucketTokenURL is where Bitbucket OAuth consumers get access tokens.
+const bitbucketTokenURL = "https://bitbucket.org/site/oauth2/access_token"
+
+// oauth2TokenMargin is how long before expiry an OAuth2 access token is
+// replaced.
+const oauth2TokenMargin = time.Minute
+
+// Credentials configures authentication beyond a single API token.
+type Credentials struct {
+	Mode     AuthMode
+	Token    string // AuthToken, AuthBearer and AuthJobToken
+	Username string // AuthBasic
+	Password string // AuthBasic
+
+	// ClientID and ClientSecret identify an OAuth2 client for
+	// AuthOAuth2ClientCredentials. TokenURL defaults to Bitbucket's token
+	// endpoint and must be set for other forges.
+	ClientID     string
+	ClientSecret string
+	TokenURL     string
+}
+
+// validate checks that creds fit the forge they are used with.
+func (creds Credentials) validate(ft ForgeType) error {
+	switch creds.Mode {
+	case "", AuthToken, AuthBearer:
+		if creds.Token == "" {
+			return fmt.Errorf("auth mode %q needs a token", creds.mode())
+		}
+	case AuthBasic:
+		if creds.Username == "" || creds.Password == "" {
+			return errors.New("basic auth needs a username and password")
+		}
+	case AuthJobToken:
+		if ft != GitLab {
+			return fmt.Errorf("job tokens are only supported by GitLab, not %s", ft)
+		}
+		if creds.Token == "" {
+			return errors.New("job token auth needs a token")
+		}
+	case AuthOAuth2ClientCredentials:
+		if creds.ClientID == "" || creds.ClientSecret == "" {
+			return errors.New("OAuth2 client credentials need a client ID and secret")
+		}
+		if creds.TokenURL == "" && ft != Bitbucket {
+			return fmt.Errorf("OAuth2 client credentials on %s need a token URL", ft)
+		}
+	default:
+		return fmt.Errorf("unknown auth mode %q", creds.Mode)
+	}
+	return nil
+}
+
+func (creds Credentials) mode() AuthMode {
+	if creds.Mode == "" {
+		return AuthToken
+	}
+	return creds.Mode
+}
+
+// authTransport applies Credentials to requests to the forge's API host.
+type authTransport struct {
+	base  http.RoundTripper
+	host  string
+	forge ForgeType
+	creds Credentials
+
+	mu      sync.Mutex
+	token   string // OAuth2 access token
+	expires time.Time
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.ToLower(req.URL.Host) != t.host {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	// GitLab's client sends an empty Private-Token when it has no token.
+	req.Header.Del("Private-Token")
+
+	switch t.creds.mode() {
+	case AuthToken:
+		setAuthHeader(req.Header, t.forge, t.creds.Token)
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+t.creds.Token)
+	case AuthBasic:
+		req.SetBasicAuth(t.creds.Username, t.creds.Password)
+	case AuthJobToken:
+		req.Header.Del("Authorization")
+		req.Header.Set("Job-Token", t.creds.Token)
+	case AuthOAuth2ClientCredentials:
+		token, err := t.accessToken(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// accessToken returns the cached OAuth2 access token, running the client
+// credentials grant again when it is missing or close to expiry.
+func (t *authTransport) accessToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && time.Until(t.expires) > oauth2TokenMargin {
+		return t.token, nil
+	}
+
+	tokenURL := t.creds.TokenURL
+	if tokenURL == "" {
+		tokenURL = bitbucketTokenURL
+	}
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(t.creds.ClientID, t.creds.ClientSecret)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return "", fmt.Errorf("oauth2: requesting access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("oauth2: requesting access token: %w",
+			&HTTPError{StatusCode: resp.StatusCode, URL: tokenURL, Body: string(body)})
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("oauth2: decoding access token: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return "", errors.New("oauth2: token response has no access_token")
+	}
+	t.token = tok.AccessToken
+	t.expires = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return t.token, nil
+}
+
+// authClient wraps hc to authenticate requests to apiHost with creds.
+func authClient(hc *http.Client, apiHost string, ft ForgeType, creds Credentials) (*http.Client, error) {
+	if err := creds.validate(ft); err != nil {
+		return nil, err
+	}
+	return wrapTransport(hc, func(base http.RoundTripper) http.RoundTripper {
+		return &authTransport{base: base, host: apiHost, forge: ft, creds: creds}
+	}), nil
+}
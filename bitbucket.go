@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -75,29 +78,50 @@ type bbTag struct {
 }
 
 func (f *bitbucketForge) getJSON(ctx context.Context, url string, v any) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	body, err := f.get(ctx, url)
 	if err != nil {
 		return err
 	}
+	defer body.Close()
+	return json.NewDecoder(body).Decode(v)
+}
+
+func (f *bitbucketForge) getText(ctx context.Context, url string) (string, error) {
+	body, err := f.get(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	return string(data), err
+}
+
+// get issues an authenticated GET and returns the body of a 200 response.
+func (f *bitbucketForge) get(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
 	if f.token != "" {
 		req.Header.Set("Authorization", "Bearer "+f.token)
 	}
 
 	resp, err := f.httpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return ErrNotFound
+		resp.Body.Close()
+		return nil, ErrNotFound
 	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return &HTTPError{StatusCode: resp.StatusCode, URL: url, Body: string(body)}
+		resp.Body.Close()
+		return nil, &HTTPError{StatusCode: resp.StatusCode, URL: url, Body: string(body)}
 	}
 
-	return json.NewDecoder(resp.Body).Decode(v)
+	return resp.Body, nil
 }
 
 func convertBitbucketRepo(bb bbRepository) Repository {
@@ -112,6 +136,10 @@ func convertBitbucketRepo(bb bbRepository) Repository {
 		HasIssues:   bb.HasIssues,
 		HTMLURL:     bb.Links.HTML.Href,
 		LogoURL:     bb.Links.Avatar.Href,
+		// Bitbucket Cloud has no stars, topics or archiving, so those
+		// fields are never populated.
+		FieldsPopulated: FieldDescription | FieldHomepage | FieldLanguage |
+			FieldDefaultBranch | FieldSize,
 	}
 
 	if bb.Owner != nil {
@@ -127,17 +155,17 @@ func convertBitbucketRepo(bb bbRepository) Repository {
 		result.SourceName = bb.Parent.FullName
 	}
 
-	if t, err := time.Parse(time.RFC3339, bb.CreatedOn); err == nil {
-		result.CreatedAt = t
-	}
-	if t, err := time.Parse(time.RFC3339, bb.UpdatedOn); err == nil {
-		result.UpdatedAt = t
-	}
+	result.CreatedAt = bbParseTime(bb.CreatedOn)
+	result.UpdatedAt = bbParseTime(bb.UpdatedOn)
 
 	return result
 }
 
 func (f *bitbucketForge) FetchRepository(ctx context.Context, owner, repo string) (*Repository, error) {
+	return f.FetchRepositoryWithOptions(ctx, owner, repo, FetchOptions{})
+}
+
+func (f *bitbucketForge) FetchRepositoryWithOptions(ctx context.Context, owner, repo string, opts FetchOptions) (*Repository, error) {
 	url := fmt.Sprintf("%s/repositories/%s/%s", bitbucketAPI, owner, repo)
 	var bb bbRepository
 	if err := f.getJSON(ctx, url, &bb); err != nil {
@@ -145,9 +173,73 @@ func (f *bitbucketForge) FetchRepository(ctx context.Context, owner, repo string
 	}
 
 	result := convertBitbucketRepo(bb)
+
+	if opts.Extended {
+		var watchers, forks bbCountResponse
+		if err := f.getJSON(ctx, url+"/watchers?fields=size", &watchers); err != nil {
+			return nil, err
+		}
+		if err := f.getJSON(ctx, url+"/forks?fields=size", &forks); err != nil {
+			return nil, err
+		}
+		result.SubscribersCount = watchers.Size
+		result.ForksCount = forks.Size
+		result.FieldsPopulated |= FieldSubscribersCount | FieldForksCount
+	}
+	if opts.IssueCounts {
+		counts, err := f.FetchIssueCounts(ctx, owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		result.OpenIssuesCount = counts.OpenIssues
+		result.OpenPullRequestsCount = counts.OpenPullRequests
+		result.FieldsPopulated |= FieldOpenIssuesCount | FieldOpenPullRequestsCount
+	}
+	// Bitbucket has no license API, so files are the only source.
+	if opts.DetectLicense && result.DefaultBranch != "" {
+		if err := f.detectLicense(ctx, url, &result); err != nil {
+			return nil, err
+		}
+	}
+
 	return &result, nil
 }
 
+type bbSrcResponse struct {
+	Values []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	} `json:"values"`
+	Next string `json:"next"`
+}
+
+func (f *bitbucketForge) detectLicense(ctx context.Context, repoURL string, result *Repository) error {
+	src := repoURL + "/src/" + escapePath(result.DefaultBranch) + "/"
+
+	var names []string
+	next := src + "?pagelen=100"
+	for next != "" {
+		var page bbSrcResponse
+		if err := f.getJSON(ctx, next, &page); err != nil {
+			// Empty repositories have no tree.
+			if errors.Is(err, ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+		for _, v := range page.Values {
+			if v.Type == "commit_file" {
+				names = append(names, v.Path)
+			}
+		}
+		next = page.Next
+	}
+
+	return detectRepositoryLicense(result, names, func(name string) (string, error) {
+		return f.getText(ctx, src+escapePath(name))
+	})
+}
+
 type bbReposResponse struct {
 	Values []bbRepository `json:"values"`
 	Next   string         `json:"next"`
@@ -198,3 +290,348 @@ func (f *bitbucketForge) FetchTags(ctx context.Context, owner, repo string) ([]T
 	}
 	return allTags, nil
 }
+
+type bbUser struct {
+	Nickname    string `json:"nickname"`
+	DisplayName string `json:"display_name"`
+}
+
+type bbIssue struct {
+	ID        int     `json:"id"`
+	Title     string  `json:"title"`
+	State     string  `json:"state"`
+	Reporter  *bbUser `json:"reporter"`
+	Component *struct {
+		Name string `json:"name"`
+	} `json:"component"`
+	Links struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+	CreatedOn string `json:"created_on"`
+	UpdatedOn string `json:"updated_on"`
+}
+
+type bbIssuesResponse struct {
+	Values []bbIssue `json:"values"`
+	Next   string    `json:"next"`
+}
+
+type bbBranchRef struct {
+	Branch struct {
+		Name string `json:"name"`
+	} `json:"branch"`
+}
+
+type bbPullRequest struct {
+	ID          int         `json:"id"`
+	Title       string      `json:"title"`
+	State       string      `json:"state"`
+	Draft       bool        `json:"draft"`
+	Author      *bbUser     `json:"author"`
+	Source      bbBranchRef `json:"source"`
+	Destination bbBranchRef `json:"destination"`
+	Links       struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+	CreatedOn string `json:"created_on"`
+	UpdatedOn string `json:"updated_on"`
+}
+
+type bbPullRequestsResponse struct {
+	Values []bbPullRequest `json:"values"`
+	Next   string          `json:"next"`
+}
+
+// bbCountResponse is a paginated response requested with fields=size.
+type bbCountResponse struct {
+	Size int `json:"size"`
+}
+
+// Bitbucket issue states that count as open. Everything else (resolved,
+// invalid, duplicate, wontfix, closed) is treated as closed.
+var bbOpenIssueStates = []string{"new", "open", "on hold"}
+
+func bbIssueOpen(state string) bool {
+	for _, s := range bbOpenIssueStates {
+		if state == s {
+			return true
+		}
+	}
+	return false
+}
+
+// bbIssueQuery builds the BBQL filter for an issue state filter.
+func bbIssueQuery(state StateFilter) string {
+	if state == StateAll {
+		return ""
+	}
+	q := `state="new" OR state="open" OR state="on hold"`
+	if state == StateClosed {
+		q = `NOT (` + q + `)`
+	}
+	return q
+}
+
+func bbParseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
+
+func bbUserName(u *bbUser) string {
+	if u == nil {
+		return ""
+	}
+	if u.Nickname != "" {
+		return u.Nickname
+	}
+	return u.DisplayName
+}
+
+func convertBitbucketIssue(bb bbIssue) Issue {
+	result := Issue{
+		Number:    bb.ID,
+		Title:     bb.Title,
+		State:     "closed",
+		Author:    bbUserName(bb.Reporter),
+		HTMLURL:   bb.Links.HTML.Href,
+		CreatedAt: bbParseTime(bb.CreatedOn),
+		UpdatedAt: bbParseTime(bb.UpdatedOn),
+	}
+	if bbIssueOpen(bb.State) {
+		result.State = "open"
+	}
+	if bb.Component != nil && bb.Component.Name != "" {
+		result.Labels = []string{bb.Component.Name}
+	}
+	return result
+}
+
+func convertBitbucketPullRequest(bb bbPullRequest) PullRequest {
+	result := PullRequest{
+		Number:     bb.ID,
+		Title:      bb.Title,
+		Author:     bbUserName(bb.Author),
+		HTMLURL:    bb.Links.HTML.Href,
+		Draft:      bb.Draft,
+		HeadBranch: bb.Source.Branch.Name,
+		BaseBranch: bb.Destination.Branch.Name,
+		CreatedAt:  bbParseTime(bb.CreatedOn),
+		UpdatedAt:  bbParseTime(bb.UpdatedOn),
+	}
+	switch bb.State {
+	case "OPEN":
+		result.State = "open"
+	case "MERGED":
+		result.State = "merged"
+	default: // DECLINED, SUPERSEDED
+		result.State = "closed"
+	}
+	return result
+}
+
+func (f *bitbucketForge) ListIssues(ctx context.Context, owner, repo string, opts IssueListOptions) ([]Issue, error) {
+	perPage := opts.PerPage
+	if perPage <= 0 {
+		perPage = 100
+	}
+
+	params := url.Values{"pagelen": {fmt.Sprint(perPage)}}
+	if q := bbIssueQuery(opts.State); q != "" {
+		params.Set("q", q)
+	}
+	var all []Issue
+	next := fmt.Sprintf("%s/repositories/%s/%s/issues?%s", bitbucketAPI, owner, repo, params.Encode())
+
+	for next != "" && !reachedLimit(len(all), opts.Limit) {
+		var page bbIssuesResponse
+		if err := f.getJSON(ctx, next, &page); err != nil {
+			return nil, err
+		}
+		for _, bb := range page.Values {
+			all = append(all, convertBitbucketIssue(bb))
+		}
+		next = page.Next
+	}
+	return truncate(all, opts.Limit), nil
+}
+
+func (f *bitbucketForge) ListPullRequests(ctx context.Context, owner, repo string, opts IssueListOptions) ([]PullRequest, error) {
+	perPage := opts.PerPage
+	if perPage <= 0 {
+		perPage = 50 // Bitbucket caps pull request pages at 50
+	}
+
+	params := url.Values{"pagelen": {fmt.Sprint(perPage)}}
+	switch opts.State {
+	case StateOpen:
+		params["state"] = []string{"OPEN"}
+	case StateClosed:
+		params["state"] = []string{"MERGED", "DECLINED", "SUPERSEDED"}
+	case StateAll:
+		params["state"] = []string{"OPEN", "MERGED", "DECLINED", "SUPERSEDED"}
+	}
+	var all []PullRequest
+	next := fmt.Sprintf("%s/repositories/%s/%s/pullrequests?%s", bitbucketAPI, owner, repo, params.Encode())
+
+	for next != "" && !reachedLimit(len(all), opts.Limit) {
+		var page bbPullRequestsResponse
+		if err := f.getJSON(ctx, next, &page); err != nil {
+			return nil, err
+		}
+		for _, bb := range page.Values {
+			all = append(all, convertBitbucketPullRequest(bb))
+		}
+		next = page.Next
+	}
+	return truncate(all, opts.Limit), nil
+}
+
+func (f *bitbucketForge) FetchIssueCounts(ctx context.Context, owner, repo string) (*IssueCounts, error) {
+	base := fmt.Sprintf("%s/repositories/%s/%s", bitbucketAPI, owner, repo)
+
+	var prs bbCountResponse
+	if err := f.getJSON(ctx, base+"/pullrequests?state=OPEN&fields=size", &prs); err != nil {
+		return nil, err
+	}
+
+	// The issues endpoint 404s when the issue tracker is disabled. The pull
+	// request call above already proved the repository exists.
+	params := url.Values{"q": {bbIssueQuery(StateOpen)}, "fields": {"size"}}
+	var issues bbCountResponse
+	if err := f.getJSON(ctx, base+"/issues?"+params.Encode(), &issues); err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	return &IssueCounts{
+		OpenIssues:       issues.Size,
+		OpenPullRequests: prs.Size,
+	}, nil
+}
+
+// bbQuote quotes a string for use in a BBQL filter.
+func bbQuote(s string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`) + `"`
+}
+
+func (f *bitbucketForge) Search(ctx context.Context, query SearchQuery) ([]Repository, error) {
+	// Bitbucket has no topics or stars to search on.
+	if query.Topic != "" || query.MinStars > 0 {
+		return nil, ErrNotSupported
+	}
+
+	perPage := query.PerPage
+	if perPage <= 0 {
+		perPage = 100
+	}
+	page := max(query.Page, 1)
+
+	var filters []string
+	if query.Text != "" {
+		filters = append(filters, fmt.Sprintf("(name ~ %s OR description ~ %s)", bbQuote(query.Text), bbQuote(query.Text)))
+	}
+	if query.Language != "" {
+		filters = append(filters, "language = "+bbQuote(strings.ToLower(query.Language)))
+	}
+
+	params := url.Values{
+		"pagelen": {fmt.Sprint(perPage)},
+		"page":    {fmt.Sprint(page)},
+	}
+	if len(filters) > 0 {
+		params.Set("q", strings.Join(filters, " AND "))
+	}
+	if query.Sort == SortUpdated {
+		params.Set("sort", "-updated_on")
+	}
+
+	var resp bbReposResponse
+	if err := f.getJSON(ctx, bitbucketAPI+"/repositories?"+params.Encode(), &resp); err != nil {
+		return nil, err
+	}
+	repos := make([]Repository, 0, len(resp.Values))
+	for _, bb := range resp.Values {
+		repos = append(repos, convertBitbucketRepo(bb))
+	}
+	return FilterSearchResults(repos, query), nil
+}
+
+func (f *bitbucketForge) ListForks(ctx context.Context, owner, repo string) ([]Repository, error) {
+	var all []Repository
+	next := fmt.Sprintf("%s/repositories/%s/%s/forks?pagelen=100", bitbucketAPI, owner, repo)
+
+	for next != "" {
+		var page bbReposResponse
+		if err := f.getJSON(ctx, next, &page); err != nil {
+			return nil, err
+		}
+		for _, bb := range page.Values {
+			all = append(all, convertBitbucketRepo(bb))
+		}
+		next = page.Next
+	}
+	return markForks(all, owner+"/"+repo), nil
+}
+
+type bbCommit struct {
+	Hash   string `json:"hash"`
+	Date   string `json:"date"`
+	Author struct {
+		Raw  string  `json:"raw"`
+		User *bbUser `json:"user"`
+	} `json:"author"`
+}
+
+type bbCommitsResponse struct {
+	Values []bbCommit `json:"values"`
+	Next   string     `json:"next"`
+}
+
+// bbCommitAuthor identifies a commit's author by account, or by the email
+// in the raw "Name <email>" author line for commits not linked to one.
+func bbCommitAuthor(c bbCommit) string {
+	if name := bbUserName(c.Author.User); name != "" {
+		return name
+	}
+	if addr, err := mail.ParseAddress(c.Author.Raw); err == nil {
+		return addr.Address
+	}
+	return c.Author.Raw
+}
+
+func (f *bitbucketForge) FetchActivity(ctx context.Context, owner, repo string, since time.Time) (*Activity, error) {
+	b := newActivityBuilder(since, time.Now())
+
+	// The commits endpoint spans all branches unless given one, so look up
+	// the main branch first.
+	var bb bbRepository
+	if err := f.getJSON(ctx, fmt.Sprintf("%s/repositories/%s/%s", bitbucketAPI, owner, repo), &bb); err != nil {
+		return nil, err
+	}
+	if bb.MainBranch == nil {
+		return b.result(), nil
+	}
+
+	next := fmt.Sprintf("%s/repositories/%s/%s/commits/%s?pagelen=100",
+		bitbucketAPI, owner, repo, escapePath(bb.MainBranch.Name))
+	for next != "" {
+		var page bbCommitsResponse
+		if err := f.getJSON(ctx, next, &page); err != nil {
+			return nil, err
+		}
+		next = page.Next
+		for _, c := range page.Values {
+			t := bbParseTime(c.Date)
+			if t.Before(since) {
+				b.noteLastCommit(t)
+				next = ""
+				break
+			}
+			b.addCommit(bbCommitAuthor(c), t)
+		}
+	}
+	return b.result(), nil
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestBitbucketFetchRepository(t *testing.T) {
@@ -107,6 +108,126 @@ func TestBitbucketFetchRepositoryNotFound(t *testing.T) {
 	}
 }
 
+func TestBitbucketFetchRepositoryExtended(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"full_name": "atlassian/myrepo",
+			"slug":      "myrepo",
+		})
+	})
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo/watchers", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"size": 12})
+	})
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo/forks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"size": 3})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	origAPI := bitbucketAPI
+	defer func() { setBitbucketAPI(origAPI) }()
+	setBitbucketAPI(srv.URL + "/2.0")
+
+	f := newBitbucketForge("", nil)
+
+	repo, err := f.FetchRepositoryWithOptions(context.Background(), "atlassian", "myrepo", FetchOptions{Extended: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqualInt(t, "SubscribersCount", 12, repo.SubscribersCount)
+	assertEqualInt(t, "ForksCount", 3, repo.ForksCount)
+	assertEqualBool(t, "counts populated", true, repo.FieldsPopulated.Has(FieldSubscribersCount|FieldForksCount))
+	assertEqualBool(t, "StargazersCount populated", false, repo.FieldsPopulated.Has(FieldStargazersCount))
+	assertEqualBool(t, "Archived populated", false, repo.FieldsPopulated.Has(FieldArchived))
+}
+
+func TestBitbucketFetchRepositoryDetectLicense(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"full_name":  "atlassian/myrepo",
+			"slug":       "myrepo",
+			"mainbranch": map[string]any{"name": "master"},
+		})
+	})
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo/src/master/", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"values": []map[string]any{
+				{"path": "src", "type": "commit_directory"},
+				{"path": "COPYING", "type": "commit_file"},
+				{"path": "README", "type": "commit_file"},
+			},
+		})
+	})
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo/src/master/COPYING", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(bsd3LicenseText))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	origAPI := bitbucketAPI
+	defer func() { setBitbucketAPI(origAPI) }()
+	setBitbucketAPI(srv.URL + "/2.0")
+
+	f := newBitbucketForge("", nil)
+
+	repo, err := f.FetchRepositoryWithOptions(context.Background(), "atlassian", "myrepo", FetchOptions{DetectLicense: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "License", "BSD-3-Clause", repo.License)
+	assertEqualBool(t, "License populated", true, repo.FieldsPopulated.Has(FieldLicense))
+}
+
+func TestBitbucketDetectLicenseBranchPath(t *testing.T) {
+	var empty bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"full_name":  "atlassian/myrepo",
+			"mainbranch": map[string]any{"name": "release/1.x"},
+		})
+	})
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo/src/release/1.x/", func(w http.ResponseWriter, r *http.Request) {
+		if empty {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"values": []map[string]any{{"path": "LICENSE", "type": "commit_file"}},
+		})
+	})
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo/src/release/1.x/LICENSE", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(bsd3LicenseText))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	origAPI := bitbucketAPI
+	defer func() { setBitbucketAPI(origAPI) }()
+	setBitbucketAPI(srv.URL + "/2.0")
+
+	f := newBitbucketForge("", nil)
+
+	repo, err := f.FetchRepositoryWithOptions(context.Background(), "atlassian", "myrepo", FetchOptions{DetectLicense: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "License", "BSD-3-Clause", repo.License)
+
+	// An empty repository has no source listing, which just means no license.
+	empty = true
+	repo, err = f.FetchRepositoryWithOptions(context.Background(), "atlassian", "myrepo", FetchOptions{DetectLicense: true})
+	if err != nil {
+		t.Fatalf("unexpected error for an empty repository: %v", err)
+	}
+	assertEqual(t, "License", "", repo.License)
+}
+
 func TestBitbucketListRepositories(t *testing.T) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /2.0/repositories/atlassian", func(w http.ResponseWriter, r *http.Request) {
@@ -211,3 +332,245 @@ func TestBitbucketFetchTags(t *testing.T) {
 	assertEqual(t, "Tag[1].Name", "v0.1.0", tags[1].Name)
 	assertEqual(t, "Tag[1].Commit", "fff666", tags[1].Commit)
 }
+
+func TestBitbucketListIssues(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo/issues", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("q"); got != `state="new" OR state="open" OR state="on hold"` {
+			t.Errorf("unexpected query %q", got)
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"values": []map[string]any{
+				{
+					"id":        7,
+					"title":     "Broken build",
+					"state":     "new",
+					"reporter":  map[string]any{"nickname": "erin"},
+					"component": map[string]any{"name": "ci"},
+				},
+			},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	origAPI := bitbucketAPI
+	defer func() { setBitbucketAPI(origAPI) }()
+	setBitbucketAPI(srv.URL + "/2.0")
+
+	f := newBitbucketForge("", nil)
+
+	issues, err := f.ListIssues(context.Background(), "atlassian", "myrepo", IssueListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	assertEqual(t, "State", "open", issues[0].State)
+	assertEqual(t, "Author", "erin", issues[0].Author)
+	assertSliceEqual(t, "Labels", []string{"ci"}, issues[0].Labels)
+}
+
+func TestBitbucketListPullRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo/pullrequests", func(w http.ResponseWriter, r *http.Request) {
+		assertSliceEqual(t, "state", []string{"MERGED", "DECLINED", "SUPERSEDED"}, r.URL.Query()["state"])
+		json.NewEncoder(w).Encode(map[string]any{
+			"values": []map[string]any{
+				{
+					"id":          11,
+					"title":       "Merge me",
+					"state":       "MERGED",
+					"source":      map[string]any{"branch": map[string]any{"name": "feature"}},
+					"destination": map[string]any{"branch": map[string]any{"name": "master"}},
+				},
+				{"id": 10, "title": "Nope", "state": "DECLINED"},
+			},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	origAPI := bitbucketAPI
+	defer func() { setBitbucketAPI(origAPI) }()
+	setBitbucketAPI(srv.URL + "/2.0")
+
+	f := newBitbucketForge("", nil)
+
+	prs, err := f.ListPullRequests(context.Background(), "atlassian", "myrepo", IssueListOptions{State: StateClosed})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prs) != 2 {
+		t.Fatalf("expected 2 pull requests, got %d", len(prs))
+	}
+	assertEqual(t, "prs[0].State", "merged", prs[0].State)
+	assertEqual(t, "prs[0].HeadBranch", "feature", prs[0].HeadBranch)
+	assertEqual(t, "prs[0].BaseBranch", "master", prs[0].BaseBranch)
+	assertEqual(t, "prs[1].State", "closed", prs[1].State)
+}
+
+func TestBitbucketFetchIssueCountsTrackerDisabled(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo/pullrequests", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"size": 4})
+	})
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo/issues", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	origAPI := bitbucketAPI
+	defer func() { setBitbucketAPI(origAPI) }()
+	setBitbucketAPI(srv.URL + "/2.0")
+
+	f := newBitbucketForge("", nil)
+
+	counts, err := f.FetchIssueCounts(context.Background(), "atlassian", "myrepo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqualInt(t, "OpenIssues", 0, counts.OpenIssues)
+	assertEqualInt(t, "OpenPullRequests", 4, counts.OpenPullRequests)
+}
+
+func TestBitbucketSearch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /2.0/repositories", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assertEqual(t, "q", `(name ~ "plugin" OR description ~ "plugin") AND language = "java"`, q.Get("q"))
+		assertEqual(t, "sort", "-updated_on", q.Get("sort"))
+		assertEqual(t, "page", "1", q.Get("page"))
+		json.NewEncoder(w).Encode(map[string]any{
+			"values": []map[string]any{
+				{"full_name": "atlassian/stash-example-plugin", "slug": "stash-example-plugin", "language": "java"},
+			},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	origAPI := bitbucketAPI
+	defer func() { setBitbucketAPI(origAPI) }()
+	setBitbucketAPI(srv.URL + "/2.0")
+
+	f := newBitbucketForge("", nil)
+
+	repos, err := f.Search(context.Background(), SearchQuery{Text: "plugin", Language: "Java", Sort: SortUpdated})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repos) != 1 {
+		t.Fatalf("expected 1 repo, got %d", len(repos))
+	}
+	assertEqual(t, "FullName", "atlassian/stash-example-plugin", repos[0].FullName)
+
+	if _, err := f.Search(context.Background(), SearchQuery{Topic: "cli"}); err != ErrNotSupported {
+		t.Errorf("expected ErrNotSupported for topic search, got %v", err)
+	}
+}
+
+func TestBitbucketListForks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo/forks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"values": []map[string]any{
+				{"full_name": "someone/myrepo", "slug": "myrepo"},
+			},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	origAPI := bitbucketAPI
+	defer func() { setBitbucketAPI(origAPI) }()
+	setBitbucketAPI(srv.URL + "/2.0")
+
+	f := newBitbucketForge("", nil)
+
+	forks, err := f.ListForks(context.Background(), "atlassian", "myrepo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(forks) != 1 {
+		t.Fatalf("expected 1 fork, got %d", len(forks))
+	}
+	assertEqualBool(t, "Fork", true, forks[0].Fork)
+	assertEqual(t, "SourceName", "atlassian/myrepo", forks[0].SourceName)
+}
+
+func TestBitbucketFetchActivity(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	since := now.Add(-24 * time.Hour)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"full_name": "atlassian/myrepo", "mainbranch": map[string]any{"name": "main"}})
+	})
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo/commits/main", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"values": []map[string]any{
+				{"hash": "1", "date": now.Format(time.RFC3339), "author": map[string]any{"raw": "A <a@example.com>", "user": map[string]any{"nickname": "anna"}}},
+				{"hash": "2", "date": now.Add(-time.Hour).Format(time.RFC3339), "author": map[string]any{"raw": "B <b@example.com>"}},
+				{"hash": "4", "date": now.Add(-2 * time.Hour).Format(time.RFC3339), "author": map[string]any{"raw": "Bea <b@example.com>"}},
+				{"hash": "3", "date": now.Add(-48 * time.Hour).Format(time.RFC3339), "author": map[string]any{"raw": "C <c@example.com>"}},
+			},
+			"next": "http://should-not-be-fetched.invalid/",
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	origAPI := bitbucketAPI
+	defer func() { setBitbucketAPI(origAPI) }()
+	setBitbucketAPI(srv.URL + "/2.0")
+
+	f := newBitbucketForge("", nil)
+
+	a, err := f.FetchActivity(context.Background(), "atlassian", "myrepo", since)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqualInt(t, "TotalCommits", 3, a.TotalCommits)
+	assertEqualInt(t, "Committers", 2, a.Committers) // anna, and b@example.com under two names
+	assertEqual(t, "LastCommitAt", now.Format(time.RFC3339), a.LastCommitAt.Format(time.RFC3339))
+}
+
+func TestBitbucketFetchActivitySlashedBranch(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"full_name": "atlassian/myrepo", "mainbranch": map[string]any{"name": "release/1.x"}})
+	})
+	mux.HandleFunc("GET /2.0/repositories/atlassian/myrepo/commits/release/1.x", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"values": []map[string]any{
+				{"hash": "1", "date": now.Format(time.RFC3339), "author": map[string]any{"raw": "A <a@example.com>"}},
+			},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	origAPI := bitbucketAPI
+	defer func() { setBitbucketAPI(origAPI) }()
+	setBitbucketAPI(srv.URL + "/2.0")
+
+	f := newBitbucketForge("", nil)
+
+	a, err := f.FetchActivity(context.Background(), "atlassian", "myrepo", now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqualInt(t, "TotalCommits", 1, a.TotalCommits)
+}
@@ -0,0 +1,259 @@
+package forges
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// commandTokenTTL is how long a token printed by a token_command is reused
+// before the command runs again.
+const commandTokenTTL = 5 * time.Minute
+
+// Config describes a Client: the forges it talks to and where their tokens
+// come from. LoadConfig reads one from a file, and NewClientFromConfig
+// turns it into a Client.
+type Config struct {
+	Forges []DomainConfig `json:"forges" yaml:"forges" toml:"forges"`
+	// InsecureHosts may be reached over plain http://, as for
+	// WithInsecureHTTP.
+	InsecureHosts []string `json:"insecure_hosts" yaml:"insecure_hosts" toml:"insecure_hosts"`
+	// CredentialSources are asked, in order, for tokens of domains that
+	// have no token source of their own: "env", "netrc", "git", "gh" and
+	// "glab" name EnvCredentials, NetrcCredentials, GitCredentials,
+	// GHCLICredentials and GlabCLICredentials with their default paths.
+	CredentialSources []string `json:"credential_sources" yaml:"credential_sources" toml:"credential_sources"`
+}
+
+// DomainConfig configures one forge in a Config. At most one token source
+// may be set; without one, the Config's credential sources are used.
+type DomainConfig struct {
+	// Domain is the domain or base URL, as for WithForge.
+	Domain string `json:"domain" yaml:"domain" toml:"domain"`
+	// Type is the forge software. It may be left out for the public forges
+	// the Client knows, such as github.com.
+	Type       ForgeType `json:"type" yaml:"type" toml:"type"`
+	APIBaseURL string    `json:"api_base_url" yaml:"api_base_url" toml:"api_base_url"`
+	Aliases    []string  `json:"aliases" yaml:"aliases" toml:"aliases"`
+
+	// TokenEnv names an environment variable holding the token, read on
+	// every request.
+	TokenEnv string `json:"token_env" yaml:"token_env" toml:"token_env"`
+	// TokenFile is a file holding the token, read on every request.
+	TokenFile string `json:"token_file" yaml:"token_file" toml:"token_file"`
+	// TokenCommand is a command printing the token, like "gh auth token".
+	// It is split on spaces and run without a shell, and its output is
+	// reused for five minutes.
+	TokenCommand string `json:"token_command" yaml:"token_command" toml:"token_command"`
+
+	// RateLimit caps requests per second to the forge, in bursts of up to
+	// Burst, as for WithRateLimit.
+	RateLimit float64  `json:"rate_limit" yaml:"rate_limit" toml:"rate_limit"`
+	Burst     int      `json:"burst" yaml:"burst" toml:"burst"`
+	Timeout   Duration `json:"timeout" yaml:"timeout" toml:"timeout"`
+}
+
+// Duration is a time.Duration written in config files as a string like
+// "30s" or "1m30s".
+type Duration time.Duration
+
+// UnmarshalText parses a duration string.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText formats the duration as a string.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// LoadConfig reads a Config from a JSON, YAML or TOML file, chosen by its
+// extension: .json, .yaml or .yml, or .toml. Unknown keys are errors, so
+// typos don't silently leave a forge unconfigured.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parseConfig(data, strings.ToLower(filepath.Ext(path)))
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func parseConfig(data []byte, ext string) (*Config, error) {
+	cfg := &Config{}
+	switch ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, err
+		}
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return nil, err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("unknown key %q", undecoded[0].String())
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", ext)
+	}
+	return cfg, nil
+}
+
+// NewClientFromConfig creates a Client configured by cfg. opts are applied
+// after the configuration, so they can add to or override it.
+func NewClientFromConfig(cfg *Config, opts ...Option) (*Client, error) {
+	cfgOpts, err := cfg.options()
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(append(cfgOpts, opts...)...), nil
+}
+
+// options translates cfg into the equivalent Client options.
+func (cfg *Config) options() ([]Option, error) {
+	var opts []Option
+	if len(cfg.InsecureHosts) > 0 {
+		opts = append(opts, WithInsecureHTTP(cfg.InsecureHosts...))
+	}
+
+	providers := make(map[string]CredentialProvider)
+	for i, d := range cfg.Forges {
+		key, _, err := parseRegistration(d.Domain)
+		if err != nil {
+			return nil, fmt.Errorf("forges[%d]: %w", i, err)
+		}
+		if err := d.validate(key); err != nil {
+			return nil, fmt.Errorf("forge %s: %w", key, err)
+		}
+
+		if d.Type != "" {
+			opts = append(opts, WithForge(d.Domain, d.Type, ""))
+		}
+		if d.APIBaseURL != "" {
+			opts = append(opts, WithAPIBaseURL(key, d.APIBaseURL))
+		}
+		for _, alias := range d.Aliases {
+			opts = append(opts, WithDomainAlias(alias, key))
+		}
+		if d.RateLimit > 0 {
+			opts = append(opts, WithRateLimit(key, d.RateLimit, d.Burst))
+		}
+		if d.Timeout > 0 {
+			opts = append(opts, WithTimeout(key, time.Duration(d.Timeout)))
+		}
+		if p := d.tokenSource(key); p != nil {
+			providers[key] = p
+		}
+	}
+
+	chain := []CredentialProvider{}
+	if len(providers) > 0 {
+		chain = append(chain, CredentialProviderFunc(func(ctx context.Context, domain string) (string, error) {
+			if p, ok := providers[domain]; ok {
+				return p.Token(ctx, domain)
+			}
+			return "", nil
+		}))
+	}
+	for _, name := range cfg.CredentialSources {
+		p, err := namedCredentialSource(name)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, p)
+	}
+	if len(chain) > 0 {
+		opts = append(opts, WithCredentialProvider(ChainCredentials(chain...)))
+	}
+	return opts, nil
+}
+
+// validate checks an entry registered under key.
+func (d DomainConfig) validate(key string) error {
+	if d.Type == "" {
+		if _, ok := knownForgeTypes[key]; !ok {
+			return errors.New("type is required for domains other than the public forges")
+		}
+	} else if _, ok := lookupForgeFactory(d.Type); !ok {
+		return fmt.Errorf("unsupported forge type %q", d.Type)
+	}
+
+	sources := 0
+	for _, s := range []string{d.TokenEnv, d.TokenFile, d.TokenCommand} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return errors.New("only one of token_env, token_file and token_command may be set")
+	}
+	if d.TokenCommand != "" && len(strings.Fields(d.TokenCommand)) == 0 {
+		return errors.New("token_command is blank")
+	}
+	if d.RateLimit < 0 || d.Burst < 0 || d.Timeout < 0 {
+		return errors.New("rate_limit, burst and timeout can't be negative")
+	}
+	return nil
+}
+
+// tokenSource returns the provider for the token source of the entry
+// registered under key, or nil.
+func (d DomainConfig) tokenSource(key string) CredentialProvider {
+	switch {
+	case d.TokenEnv != "":
+		name := d.TokenEnv
+		return CredentialProviderFunc(func(context.Context, string) (string, error) {
+			return os.Getenv(name), nil
+		})
+	case d.TokenFile != "":
+		return FileCredentials(key, d.TokenFile)
+	case d.TokenCommand != "":
+		args := strings.Fields(d.TokenCommand)
+		return CacheCredentials(CommandCredentials(key, args[0], args[1:]...), commandTokenTTL)
+	}
+	return nil
+}
+
+// namedCredentialSource maps a credential_sources entry to its provider.
+func namedCredentialSource(name string) (CredentialProvider, error) {
+	switch name {
+	case "env":
+		return EnvCredentials(), nil
+	case "netrc":
+		return NetrcCredentials(""), nil
+	case "git":
+		return CacheCredentials(GitCredentials(), commandTokenTTL), nil
+	case "gh":
+		return GHCLICredentials(""), nil
+	case "glab":
+		return GlabCLICredentials(""), nil
+	}
+	return nil, fmt.Errorf("unknown credential source %q", name)
+}
@@ -0,0 +1,214 @@
+package forges
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	want := DomainConfig{
+		Domain:    "https://git.example.com/gitlab",
+		Type:      GitLab,
+		Aliases:   []string{"ssh.example.com"},
+		TokenEnv:  "EXAMPLE_TOKEN",
+		RateLimit: 10,
+		Burst:     5,
+		Timeout:   Duration(30 * time.Second),
+	}
+	files := map[string]string{
+		"forges.json": `{
+  "insecure_hosts": ["localhost"],
+  "credential_sources": ["env"],
+  "forges": [{
+    "domain": "https://git.example.com/gitlab",
+    "type": "gitlab",
+    "aliases": ["ssh.example.com"],
+    "token_env": "EXAMPLE_TOKEN",
+    "rate_limit": 10,
+    "burst": 5,
+    "timeout": "30s"
+  }]
+}`,
+		"forges.yaml": `insecure_hosts: [localhost]
+credential_sources: [env]
+forges:
+  - domain: https://git.example.com/gitlab
+    type: gitlab
+    aliases: [ssh.example.com]
+    token_env: EXAMPLE_TOKEN
+    rate_limit: 10
+    burst: 5
+    timeout: 30s
+`,
+		"forges.toml": `insecure_hosts = ["localhost"]
+credential_sources = ["env"]
+
+[[forges]]
+domain = "https://git.example.com/gitlab"
+type = "gitlab"
+aliases = ["ssh.example.com"]
+token_env = "EXAMPLE_TOKEN"
+rate_limit = 10
+burst = 5
+timeout = "30s"
+`,
+	}
+	for name, content := range files {
+		cfg, err := LoadConfig(writeConfig(t, name, content))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		assertSliceEqual(t, name+" insecure_hosts", []string{"localhost"}, cfg.InsecureHosts)
+		assertSliceEqual(t, name+" credential_sources", []string{"env"}, cfg.CredentialSources)
+		if len(cfg.Forges) != 1 {
+			t.Fatalf("%s: expected 1 forge, got %d", name, len(cfg.Forges))
+		}
+		got := cfg.Forges[0]
+		assertEqual(t, name+" domain", want.Domain, got.Domain)
+		assertEqual(t, name+" type", string(want.Type), string(got.Type))
+		assertSliceEqual(t, name+" aliases", want.Aliases, got.Aliases)
+		assertEqual(t, name+" token_env", want.TokenEnv, got.TokenEnv)
+		assertEqualInt(t, name+" burst", want.Burst, got.Burst)
+		if got.RateLimit != want.RateLimit || got.Timeout != want.Timeout {
+			t.Errorf("%s: got rate_limit %v timeout %v", name, got.RateLimit, time.Duration(got.Timeout))
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	files := map[string]string{
+		"unknown.json":  `{"forges": [{"domain": "github.com", "tokn_env": "X"}]}`,
+		"unknown.yaml":  "forges:\n  - domain: github.com\n    tokn_env: X\n",
+		"unknown.toml":  "[[forges]]\ndomain = \"github.com\"\ntokn_env = \"X\"\n",
+		"duration.yaml": "forges:\n  - domain: github.com\n    timeout: soon\n",
+		"forges.ini":    "",
+	}
+	for name, content := range files {
+		if _, err := LoadConfig(writeConfig(t, name, content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	cfg, err := LoadConfig(writeConfig(t, "empty.yaml", ""))
+	if err != nil {
+		t.Fatalf("empty: unexpected error: %v", err)
+	}
+	if len(cfg.Forges) != 0 {
+		t.Errorf("empty: expected no forges, got %d", len(cfg.Forges))
+	}
+}
+
+func TestNewClientFromConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		d    DomainConfig
+	}{
+		{"missing type", DomainConfig{Domain: "git.example.com"}},
+		{"unknown type", DomainConfig{Domain: "git.example.com", Type: "sourcehut"}},
+		{"two token sources", DomainConfig{Domain: "github.com", TokenEnv: "A", TokenFile: "/b"}},
+		{"blank command", DomainConfig{Domain: "github.com", TokenCommand: "  "}},
+		{"negative rate", DomainConfig{Domain: "github.com", RateLimit: -1}},
+	}
+	for _, tt := range tests {
+		if _, err := NewClientFromConfig(&Config{Forges: []DomainConfig{tt.d}}); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+	if _, err := NewClientFromConfig(&Config{CredentialSources: []string{"keychain"}}); err == nil {
+		t.Error("expected error for unknown credential source")
+	}
+}
+
+func TestNewClientFromConfigTokenSources(t *testing.T) {
+	var auths []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world", func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(map[string]any{"full_name": "octocat/hello-world"})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	t.Setenv("CONFIG_TEST_TOKEN", "from-env")
+	tokenFile := writeConfig(t, "token", "from-file\n")
+	tests := []struct {
+		name string
+		d    DomainConfig
+		want string
+	}{
+		{"env", DomainConfig{TokenEnv: "CONFIG_TEST_TOKEN"}, "Bearer from-env"},
+		{"file", DomainConfig{TokenFile: tokenFile}, "Bearer from-file"},
+		{"command", DomainConfig{TokenCommand: "echo from-command"}, "Bearer from-command"},
+		{"none", DomainConfig{}, ""},
+	}
+	for _, tt := range tests {
+		tt.d.Domain = srv.URL
+		tt.d.Type = GitHub
+		c, err := NewClientFromConfig(&Config{
+			Forges:        []DomainConfig{tt.d},
+			InsecureHosts: []string{"127.0.0.1"},
+		})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		auths = nil
+		if _, err := c.FetchRepository(context.Background(), srv.URL+"/octocat/hello-world"); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		assertSliceEqual(t, tt.name, []string{tt.want}, auths)
+	}
+}
+
+func TestNewClientFromConfigLimits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "slow") {
+			time.Sleep(200 * time.Millisecond)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"full_name": "octocat/hello-world"})
+	}))
+	defer srv.Close()
+
+	c, err := NewClientFromConfig(&Config{
+		Forges: []DomainConfig{{
+			Domain:    srv.URL,
+			Type:      GitHub,
+			RateLimit: 0.01,
+			Burst:     2,
+			Timeout:   Duration(50 * time.Millisecond),
+		}},
+		InsecureHosts: []string{"127.0.0.1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := c.FetchRepository(context.Background(), srv.URL+"/octocat/slow"); err == nil {
+		t.Error("expected the request to time out")
+	}
+	if _, err := c.FetchRepository(context.Background(), srv.URL+"/octocat/hello-world"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The burst is used up, so the next request waits beyond its deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.FetchRepository(ctx, srv.URL+"/octocat/hello-world"); err == nil {
+		t.Error("expected the rate limit to hold the request back")
+	}
+}
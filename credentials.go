@@ -0,0 +1,408 @@
+package forges
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CredentialProvider supplies API tokens on demand. It is asked for a token
+// on every request, so tokens can be rotated without rebuilding the Client;
+// wrap slow providers with CacheCredentials. domain is the key the forge is
+// registered under: the host, any non-default port and any path prefix. An
+// empty token with a nil error means the provider has none and the request
+// goes out anonymously.
+type CredentialProvider interface {
+	Token(ctx context.Context, domain string) (string, error)
+}
+
+// CredentialProviderFunc adapts a function to CredentialProvider.
+type CredentialProviderFunc func(ctx context.Context, domain string) (string, error)
+
+// Token calls fn.
+func (fn CredentialProviderFunc) Token(ctx context.Context, domain string) (string, error) {
+	return fn(ctx, domain)
+}
+
+// ChainCredentials asks each provider in turn and returns the first token
+// found. An error from any provider stops the search.
+func ChainCredentials(providers ...CredentialProvider) CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context, domain string) (string, error) {
+		for _, p := range providers {
+			token, err := p.Token(ctx, domain)
+			if err != nil || token != "" {
+				return token, err
+			}
+		}
+		return "", nil
+	})
+}
+
+type cachedCredential struct {
+	token   string
+	fetched time.Time
+}
+
+// CacheCredentials remembers the tokens p returns for ttl, so providers that
+// run a process or read a file aren't consulted on every request. Errors
+// aren't cached.
+func CacheCredentials(p CredentialProvider, ttl time.Duration) CredentialProvider {
+	var mu sync.Mutex
+	cache := make(map[string]cachedCredential)
+	return CredentialProviderFunc(func(ctx context.Context, domain string) (string, error) {
+		mu.Lock()
+		c, ok := cache[domain]
+		mu.Unlock()
+		if ok && time.Since(c.fetched) < ttl {
+			return c.token, nil
+		}
+		token, err := p.Token(ctx, domain)
+		if err != nil {
+			return "", err
+		}
+		mu.Lock()
+		cache[domain] = cachedCredential{token: token, fetched: time.Now()}
+		mu.Unlock()
+		return token, nil
+	})
+}
+
+// credentialHost returns the host part of a registration key, without its
+// port, since netrc and the CLI config files name hosts alone.
+func credentialHost(domain string) string {
+	host, _, _ := strings.Cut(domain, "/")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// envTokenRule names the variables holding a token for one forge's hosts.
+// The token applies to the host named by hostVar, or to defaultHost when
+// hostVar is unset, following the conventions of each forge's CLI.
+type envTokenRule struct {
+	hostVar     string
+	defaultHost string
+	tokenVars   []string
+}
+
+var envTokenRules = []envTokenRule{
+	{defaultHost: "github.com", tokenVars: []string{"GH_TOKEN", "GITHUB_TOKEN"}},
+	{hostVar: "GH_HOST", tokenVars: []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}},
+	{hostVar: "GITLAB_HOST", defaultHost: "gitlab.com", tokenVars: []string{"GITLAB_TOKEN"}},
+	{hostVar: "GITEA_SERVER_URL", defaultHost: "codeberg.org", tokenVars: []string{"GITEA_SERVER_TOKEN", "GITEA_TOKEN"}},
+	{defaultHost: "bitbucket.org", tokenVars: []string{"BITBUCKET_TOKEN"}},
+}
+
+// EnvCredentials reads tokens from environment variables. A per-host
+// variable, FORGES_TOKEN_ followed by the host uppercased with
+// non-alphanumerics replaced by underscores (FORGES_TOKEN_GIT_EXAMPLE_COM),
+// wins. Otherwise the forge CLIs' variables are used for the hosts they
+// apply to:
+//
+//   - GH_TOKEN or GITHUB_TOKEN for github.com
+//   - GH_ENTERPRISE_TOKEN or GITHUB_ENTERPRISE_TOKEN for the host in GH_HOST
+//   - GITLAB_TOKEN for the host in GITLAB_HOST, or gitlab.com
+//   - GITEA_SERVER_TOKEN or GITEA_TOKEN for the host in GITEA_SERVER_URL,
+//     or codeberg.org
+//   - BITBUCKET_TOKEN for bitbucket.org
+//
+// Tokens are never sent to hosts other than these, so a GitHub token can't
+// leak to an unrelated forge.
+func EnvCredentials() CredentialProvider {
+	return CredentialProviderFunc(func(_ context.Context, domain string) (string, error) {
+		host := credentialHost(domain)
+		if token := os.Getenv("FORGES_TOKEN_" + envHostName(host)); token != "" {
+			return token, nil
+		}
+		for _, rule := range envTokenRules {
+			ruleHost := rule.defaultHost
+			if rule.hostVar != "" {
+				if v := os.Getenv(rule.hostVar); v != "" {
+					ruleHost = credentialHost(registrationKey(v))
+				}
+			}
+			if ruleHost != host {
+				continue
+			}
+			for _, name := range rule.tokenVars {
+				if token := os.Getenv(name); token != "" {
+					return token, nil
+				}
+			}
+		}
+		return "", nil
+	})
+}
+
+func envHostName(host string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, host)
+}
+
+// NetrcCredentials reads the password of the matching machine entry, or of
+// the default entry, from a .netrc file. An empty path means $NETRC or
+// ~/.netrc. The file is read on every call; a missing file yields no token.
+func NetrcCredentials(path string) CredentialProvider {
+	return CredentialProviderFunc(func(_ context.Context, domain string) (string, error) {
+		p := path
+		if p == "" {
+			p = os.Getenv("NETRC")
+		}
+		if p == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", nil
+			}
+			p = filepath.Join(home, ".netrc")
+		}
+		data, err := os.ReadFile(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return netrcPassword(data, credentialHost(domain)), nil
+	})
+}
+
+// netrcPassword returns the password for host from netrc data, falling back
+// to the default entry.
+func netrcPassword(data []byte, host string) string {
+	var machine, password, fallback string
+	inDefault := false
+	fields := strings.Fields(string(data))
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			if machine == host && password != "" {
+				return password
+			}
+			machine, password, inDefault = "", "", false
+			if i+1 < len(fields) {
+				i++
+				machine = strings.ToLower(fields[i])
+			}
+		case "default":
+			if machine == host && password != "" {
+				return password
+			}
+			machine, password, inDefault = "", "", true
+		case "password":
+			if i+1 < len(fields) {
+				i++
+				if inDefault {
+					fallback = fields[i]
+				} else {
+					password = fields[i]
+				}
+			}
+		case "login", "account":
+			i++
+		}
+	}
+	if machine == host && password != "" {
+		return password
+	}
+	return fallback
+}
+
+// GitCredentials asks git's configured credential helpers for the password
+// stored for https://<host>, as `git credential fill` does. Prompting is
+// disabled, so hosts without a stored credential yield no token.
+func GitCredentials() CredentialProvider {
+	return gitCredentials("git")
+}
+
+func gitCredentials(git string) CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context, domain string) (string, error) {
+		cmd := exec.CommandContext(ctx, git, "credential", "fill")
+		cmd.Stdin = strings.NewReader("protocol=https\nhost=" + credentialHost(domain) + "\n\n")
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never")
+		out, err := cmd.Output()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(out))
+		for scanner.Scan() {
+			if password, ok := strings.CutPrefix(scanner.Text(), "password="); ok {
+				return password, nil
+			}
+		}
+		return "", nil
+	})
+}
+
+// FileCredentials reads the token for host from a file holding nothing
+// else, such as a mounted secret. Other hosts get no token, so a client
+// with several forges doesn't send it to the wrong one. The file is read on
+// every call, so rotated secrets are picked up. Unlike the CLI config
+// providers, a missing file is an error.
+func FileCredentials(host, path string) CredentialProvider {
+	host = credentialHost(registrationKey(host))
+	return CredentialProviderFunc(func(_ context.Context, domain string) (string, error) {
+		if credentialHost(domain) != host {
+			return "", nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	})
+}
+
+// CommandCredentials runs a command, such as `gh auth token` or a secrets
+// manager's CLI, and uses its trimmed output as the token for host. Other
+// hosts get no token and the command doesn't run for them. It runs
+// directly, not through a shell, on every call; wrap it with
+// CacheCredentials.
+func CommandCredentials(host, name string, args ...string) CredentialProvider {
+	host = credentialHost(registrationKey(host))
+	return CredentialProviderFunc(func(ctx context.Context, domain string) (string, error) {
+		if credentialHost(domain) != host {
+			return "", nil
+		}
+		out, err := exec.CommandContext(ctx, name, args...).Output()
+		if err != nil {
+			return "", fmt.Errorf("running %s: %w", name, err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	})
+}
+
+// GHCLICredentials reads the oauth_token gh stores per host in hosts.yml.
+// An empty path means $GH_CONFIG_DIR/hosts.yml, falling back to the gh
+// directory under the user's config directory. Tokens gh keeps in the
+// system keyring aren't visible here; use `gh auth token` for those.
+func GHCLICredentials(path string) CredentialProvider {
+	return yamlCredentials(path, "GH_CONFIG_DIR", "gh", "hosts.yml", func(doc map[string]any, host string) string {
+		return yamlString(doc, host, "oauth_token")
+	})
+}
+
+// GlabCLICredentials reads the token glab stores per host in config.yml.
+// An empty path means $GLAB_CONFIG_DIR/config.yml, falling back to the
+// glab-cli directory under the user's config directory.
+func GlabCLICredentials(path string) CredentialProvider {
+	return yamlCredentials(path, "GLAB_CONFIG_DIR", "glab-cli", "config.yml", func(doc map[string]any, host string) string {
+		return yamlString(doc, "hosts", host, "token")
+	})
+}
+
+// yamlCredentials reads a CLI's YAML config on every call and extracts the
+// token for a host with lookup.
+func yamlCredentials(path, dirVar, dirName, fileName string, lookup func(doc map[string]any, host string) string) CredentialProvider {
+	return CredentialProviderFunc(func(_ context.Context, domain string) (string, error) {
+		p := path
+		if p == "" {
+			dir := os.Getenv(dirVar)
+			if dir == "" {
+				configDir, err := os.UserConfigDir()
+				if err != nil {
+					return "", nil
+				}
+				dir = filepath.Join(configDir, dirName)
+			}
+			p = filepath.Join(dir, fileName)
+		}
+		data, err := os.ReadFile(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		var doc map[string]any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return "", fmt.Errorf("reading %s: %w", p, err)
+		}
+		return lookup(doc, credentialHost(domain)), nil
+	})
+}
+
+// yamlString follows keys through nested maps and returns the string at
+// the end, or "".
+func yamlString(doc map[string]any, keys ...string) string {
+	var v any = doc
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return ""
+		}
+		v = m[k]
+	}
+	s, _ := v.(string)
+	return s
+}
+
+// credentialTransport asks a CredentialProvider for a token on every request
+// to the forge's API host and sets it in the header the forge expects.
+// Requests to other hosts, such as redirects to download URLs, go out
+// without it.
+type credentialTransport struct {
+	base     http.RoundTripper
+	domain   string
+	host     string
+	forge    ForgeType
+	provider CredentialProvider
+}
+
+func (t *credentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.ToLower(req.URL.Host) != t.host {
+		return t.base.RoundTrip(req)
+	}
+	token, err := t.provider.Token(req.Context(), t.domain)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req = req.Clone(req.Context())
+		setAuthHeader(req.Header, t.forge, token)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// setAuthHeader sets token in the header each forge reads API tokens from.
+func setAuthHeader(h http.Header, ft ForgeType, token string) {
+	switch ft {
+	case GitLab:
+		h.Del("Authorization")
+		h.Set("Private-Token", token)
+	case Gitea, Forgejo:
+		h.Set("Authorization", "token "+token)
+	default:
+		h.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// credentialClient wraps hc to authenticate requests to apiHost with
+// tokens from p.
+func credentialClient(hc *http.Client, domain, apiHost string, ft ForgeType, p CredentialProvider) *http.Client {
+	return wrapTransport(hc, func(base http.RoundTripper) http.RoundTripper {
+		return &credentialTransport{base: base, domain: domain, host: apiHost, forge: ft, provider: p}
+	})
+}
@@ -0,0 +1,284 @@
+package forges
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvCredentials(t *testing.T) {
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_HOST", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN",
+		"GITLAB_HOST", "GITLAB_TOKEN", "GITEA_SERVER_URL", "GITEA_SERVER_TOKEN", "GITEA_TOKEN", "BITBUCKET_TOKEN"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("GITHUB_TOKEN", "gh")
+	t.Setenv("GH_HOST", "ghe.example.com")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "ghe")
+	t.Setenv("GITLAB_HOST", "https://gitlab.example.com/")
+	t.Setenv("GITLAB_TOKEN", "gl")
+	t.Setenv("GITEA_TOKEN", "gitea")
+	t.Setenv("FORGES_TOKEN_GIT_EXAMPLE_ORG", "per-host")
+
+	tests := []struct {
+		domain, want string
+	}{
+		{"github.com", "gh"},
+		{"ghe.example.com", "ghe"},
+		{"gitlab.example.com", "gl"},
+		{"gitlab.com", ""}, // GITLAB_HOST points elsewhere
+		{"codeberg.org", "gitea"},
+		{"git.example.org/gitea", "per-host"},
+		{"bitbucket.org", ""},
+		{"unrelated.example.com", ""},
+	}
+	p := EnvCredentials()
+	for _, tt := range tests {
+		got, err := p.Token(context.Background(), tt.domain)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.domain, err)
+		}
+		assertEqual(t, tt.domain, tt.want, got)
+	}
+}
+
+func TestNetrcPassword(t *testing.T) {
+	data := []byte(`
+machine github.com login octocat password gh-secret
+machine gitlab.com
+  login me
+  password gl-secret
+default login anon password fallback
+`)
+	assertEqual(t, "github.com", "gh-secret", netrcPassword(data, "github.com"))
+	assertEqual(t, "gitlab.com", "gl-secret", netrcPassword(data, "gitlab.com"))
+	assertEqual(t, "default", "fallback", netrcPassword(data, "example.com"))
+	assertEqual(t, "no default", "", netrcPassword([]byte("machine a login b password c"), "example.com"))
+}
+
+func TestNetrcCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netrc")
+	os.WriteFile(path, []byte("machine git.example.com login me password one\n"), 0o600)
+
+	p := NetrcCredentials(path)
+	token, err := p.Token(context.Background(), "git.example.com/gitlab")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "token", "one", token)
+
+	// The file is reread, so rotated passwords are picked up.
+	os.WriteFile(path, []byte("machine git.example.com login me password two\n"), 0o600)
+	token, _ = p.Token(context.Background(), "git.example.com")
+	assertEqual(t, "rotated token", "two", token)
+
+	token, err = NetrcCredentials(filepath.Join(t.TempDir(), "missing")).Token(context.Background(), "git.example.com")
+	if err != nil {
+		t.Fatalf("missing file should not be an error: %v", err)
+	}
+	assertEqual(t, "missing file", "", token)
+}
+
+func TestGitCredentials(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script")
+	}
+	git := filepath.Join(t.TempDir(), "git")
+	script := `#!/bin/sh
+read line
+case "$line" in
+  *host=git.example.com*) ;;
+  *) read line ;;
+esac
+case "$line" in
+  host=git.example.com) printf 'protocol=https\nhost=git.example.com\nusername=me\npassword=from-helper\n' ;;
+  *) echo "fatal: could not read Username" >&2; exit 128 ;;
+esac
+`
+	if err := os.WriteFile(git, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	p := gitCredentials(git)
+	token, err := p.Token(context.Background(), "git.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "token", "from-helper", token)
+
+	token, err = p.Token(context.Background(), "other.example.com")
+	if err != nil {
+		t.Fatalf("a helper without credentials should not be an error: %v", err)
+	}
+	assertEqual(t, "no credential", "", token)
+}
+
+func TestFileAndCommandCredentialsScope(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	providers := map[string]CredentialProvider{
+		"file": FileCredentials("git.example.com", path),
+	}
+	if runtime.GOOS != "windows" {
+		providers["command"] = CommandCredentials("https://git.example.com:8443", "echo", "secret")
+	}
+
+	for name, p := range providers {
+		token, err := p.Token(context.Background(), "git.example.com")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		assertEqual(t, name+" token", "secret", token)
+
+		token, err = p.Token(context.Background(), "other.example.com")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		assertEqual(t, name+" other domain", "", token)
+	}
+}
+
+func TestCLIConfigCredentials(t *testing.T) {
+	dir := t.TempDir()
+	hosts := filepath.Join(dir, "hosts.yml")
+	os.WriteFile(hosts, []byte(`github.com:
+    users:
+        octocat:
+            oauth_token: gho_abc
+    user: octocat
+    oauth_token: gho_abc
+    git_protocol: https
+ghe.example.com:
+    oauth_token: "gho_enterprise" # quoted
+`), 0o600)
+	glab := filepath.Join(dir, "config.yml")
+	os.WriteFile(glab, []byte(`git_protocol: ssh
+editor: |
+    vim
+    -u NONE
+aliases: [ci, mr]
+hosts:
+    gitlab.com:
+        token: glpat-abc
+        api_protocol: https
+        scopes:
+            - api
+            - read_user
+    gitlab.example.com: {token: glpat-self}
+`), 0o600)
+	broken := filepath.Join(dir, "broken.yml")
+	os.WriteFile(broken, []byte("hosts: [unclosed\n"), 0o600)
+
+	tests := []struct {
+		name   string
+		p      CredentialProvider
+		domain string
+		want   string
+	}{
+		{"gh", GHCLICredentials(hosts), "github.com", "gho_abc"},
+		{"gh enterprise", GHCLICredentials(hosts), "ghe.example.com", "gho_enterprise"},
+		{"gh unknown", GHCLICredentials(hosts), "gitlab.com", ""},
+		{"glab", GlabCLICredentials(glab), "gitlab.com", "glpat-abc"},
+		{"glab self-hosted", GlabCLICredentials(glab), "gitlab.example.com", "glpat-self"},
+	}
+	for _, tt := range tests {
+		got, err := tt.p.Token(context.Background(), tt.domain)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		assertEqual(t, tt.name, tt.want, got)
+	}
+
+	if _, err := GlabCLICredentials(broken).Token(context.Background(), "gitlab.com"); err == nil {
+		t.Error("expected an error for a malformed config file")
+	}
+}
+
+func TestCredentialTransportScope(t *testing.T) {
+	var downloadAuth string
+	downloads := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloadAuth = r.Header.Get("Authorization")
+	}))
+	defer downloads.Close()
+
+	var apiAuth string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiAuth = r.Header.Get("Authorization")
+		http.Redirect(w, r, downloads.URL+"/archive.tar.gz", http.StatusFound)
+	}))
+	defer api.Close()
+
+	p := CredentialProviderFunc(func(ctx context.Context, domain string) (string, error) {
+		return "secret", nil
+	})
+	hc := credentialClient(nil, "example.com", strings.TrimPrefix(api.URL, "http://"), GitHub, p)
+	resp, err := hc.Get(api.URL + "/archive")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	assertEqual(t, "API Authorization", "Bearer secret", apiAuth)
+	assertEqual(t, "download Authorization", "", downloadAuth)
+}
+
+func TestChainAndCacheCredentials(t *testing.T) {
+	calls := 0
+	counting := CredentialProviderFunc(func(ctx context.Context, domain string) (string, error) {
+		calls++
+		return "counted", nil
+	})
+	empty := CredentialProviderFunc(func(ctx context.Context, domain string) (string, error) {
+		return "", nil
+	})
+
+	p := CacheCredentials(ChainCredentials(empty, counting), time.Hour)
+	for range 3 {
+		token, err := p.Token(context.Background(), "example.com")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEqual(t, "token", "counted", token)
+	}
+	assertEqualInt(t, "calls", 1, calls)
+}
+
+func TestClientCredentialProvider(t *testing.T) {
+	var seen []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/team%2Frepo", func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Private-Token"))
+		json.NewEncoder(w).Encode(map[string]any{"id": 1, "path_with_namespace": "team/repo"})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	token := "first"
+	p := CredentialProviderFunc(func(ctx context.Context, domain string) (string, error) {
+		if domain != strings.TrimPrefix(srv.URL, "http://")+"/gitlab" {
+			return "", nil
+		}
+		return token, nil
+	})
+	c := NewClient(
+		WithGitLab(srv.URL+"/gitlab", ""),
+		WithAPIBaseURL(srv.URL+"/gitlab", srv.URL),
+		WithCredentialProvider(p),
+		WithInsecureHTTP("127.0.0.1"),
+	)
+
+	for _, next := range []string{"second", ""} {
+		if _, err := c.FetchRepository(context.Background(), srv.URL+"/gitlab/team/repo"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		token = next
+	}
+	assertSliceEqual(t, "tokens", []string{"first", "second"}, seen)
+}
@@ -11,23 +11,34 @@ import (
 // DetectForgeType probes a domain to identify which forge software it runs.
 // It checks HTTP response headers first, then falls back to API endpoints.
 func DetectForgeType(ctx context.Context, domain string) (ForgeType, error) {
-	baseURL := "https://" + domain
+	return detectForgeTypeAt(ctx, http.DefaultClient, "https://"+domain)
+}
 
-	ft, err := detectFromHeaders(ctx, baseURL)
+// detectForgeTypeAt probes the instance rooted at baseURL, which may use
+// plain HTTP, a port or a path prefix, sending requests through hc.
+func detectForgeTypeAt(ctx context.Context, hc *http.Client, baseURL string) (ForgeType, error) {
+	ft, err := detectFromHeaders(ctx, hc, baseURL)
 	if err == nil && ft != Unknown {
 		return ft, nil
 	}
 
-	return detectFromAPI(ctx, baseURL)
+	ft, err = detectFromAPI(ctx, hc, baseURL)
+	if err == nil {
+		return ft, nil
+	}
+	if ft, ok := detectRegisteredForgeType(ctx, hc, baseURL); ok {
+		return ft, nil
+	}
+	return Unknown, err
 }
 
-func detectFromHeaders(ctx context.Context, baseURL string) (ForgeType, error) {
+func detectFromHeaders(ctx context.Context, hc *http.Client, baseURL string) (ForgeType, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL, nil)
 	if err != nil {
 		return Unknown, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := hc.Do(req)
 	if err != nil {
 		return Unknown, err
 	}
@@ -49,32 +60,32 @@ func detectFromHeaders(ctx context.Context, baseURL string) (ForgeType, error) {
 	return Unknown, nil
 }
 
-func detectFromAPI(ctx context.Context, baseURL string) (ForgeType, error) {
+func detectFromAPI(ctx context.Context, hc *http.Client, baseURL string) (ForgeType, error) {
 	// Try Gitea/Forgejo /api/v1/version
-	if ft, err := probeGiteaAPI(ctx, baseURL); err == nil {
+	if ft, err := probeGiteaAPI(ctx, hc, baseURL); err == nil {
 		return ft, nil
 	}
 
 	// Try GitLab /api/v4/version
-	if ok, err := probeURL(ctx, baseURL+"/api/v4/version"); err == nil && ok {
+	if ok, err := probeURL(ctx, hc, baseURL+"/api/v4/version"); err == nil && ok {
 		return GitLab, nil
 	}
 
 	// Try GitHub Enterprise /api/v3/meta
-	if ok, err := probeURL(ctx, baseURL+"/api/v3/meta"); err == nil && ok {
+	if ok, err := probeURL(ctx, hc, baseURL+"/api/v3/meta"); err == nil && ok {
 		return GitHub, nil
 	}
 
 	return Unknown, fmt.Errorf("could not detect forge type for %s", baseURL)
 }
 
-func probeGiteaAPI(ctx context.Context, baseURL string) (ForgeType, error) {
+func probeGiteaAPI(ctx context.Context, hc *http.Client, baseURL string) (ForgeType, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/api/v1/version", nil)
 	if err != nil {
 		return Unknown, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := hc.Do(req)
 	if err != nil {
 		return Unknown, err
 	}
@@ -97,13 +108,13 @@ func probeGiteaAPI(ctx context.Context, baseURL string) (ForgeType, error) {
 	return Gitea, nil
 }
 
-func probeURL(ctx context.Context, url string) (bool, error) {
+func probeURL(ctx context.Context, hc *http.Client, url string) (bool, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return false, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := hc.Do(req)
 	if err != nil {
 		return false, err
 	}
@@ -111,3 +122,38 @@ func probeURL(ctx context.Context, url string) (bool, error) {
 
 	return resp.StatusCode == http.StatusOK, nil
 }
+
+// detectVersion asks a detected instance for its version, returning "" when
+// it won't say, as GitLab doesn't without a token.
+func detectVersion(ctx context.Context, hc *http.Client, baseURL string, ft ForgeType) string {
+	var path, field string
+	switch ft {
+	case Gitea, Forgejo:
+		path, field = "/api/v1/version", "version"
+	case GitLab:
+		path, field = "/api/v4/version", "version"
+	case GitHub:
+		path, field = "/api/v3/meta", "installed_version"
+	default:
+		return ""
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+path, nil)
+	if err != nil {
+		return ""
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return ""
+	}
+	version, _ := body[field].(string)
+	return version
+}
@@ -0,0 +1,144 @@
+package forges
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Detection records what probing a domain found.
+type Detection struct {
+	Type       ForgeType `json:"type"`
+	BaseURL    string    `json:"base_url"`
+	Version    string    `json:"version,omitempty"` // "" when the instance doesn't reveal it
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// DetectionStore keeps detection results between runs, so RegisterDomain
+// and auto-registration don't probe the same domains every time a program
+// starts. domain is the registration key: the host, any non-default port
+// and any path prefix.
+type DetectionStore interface {
+	// Load returns the stored detection for domain. ok is false when there
+	// is none, or it is too old to trust.
+	Load(ctx context.Context, domain string) (d Detection, ok bool, err error)
+	Save(ctx context.Context, domain string, d Detection) error
+}
+
+// FileDetectionStore is a DetectionStore backed by a JSON file, shared by
+// every Client and process that uses the same path.
+type FileDetectionStore struct {
+	path string
+	ttl  time.Duration
+	mu   sync.Mutex
+}
+
+// NewFileDetectionStore stores detections in the JSON file at path, which
+// is created on the first save. Detections older than ttl are ignored; a
+// ttl of 0 keeps them forever.
+func NewFileDetectionStore(path string, ttl time.Duration) *FileDetectionStore {
+	return &FileDetectionStore{path: path, ttl: ttl}
+}
+
+// Load implements DetectionStore.
+func (s *FileDetectionStore) Load(_ context.Context, domain string) (Detection, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.read()
+	if err != nil {
+		return Detection{}, false, err
+	}
+	d, ok := entries[domain]
+	if !ok || (s.ttl > 0 && time.Since(d.DetectedAt) > s.ttl) {
+		return Detection{}, false, nil
+	}
+	return d, true, nil
+}
+
+// Save implements DetectionStore. The file is replaced atomically, so
+// concurrent readers never see it half written.
+func (s *FileDetectionStore) Save(_ context.Context, domain string, d Detection) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.read()
+	if err != nil {
+		return err
+	}
+	entries[domain] = d
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func (s *FileDetectionStore) read() (map[string]Detection, error) {
+	entries := make(map[string]Detection)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// detect identifies the forge at baseURL. It sends its probes to probe,
+// which differs from baseURL when the API lives elsewhere, using the HTTP
+// client configured for key. Results are taken from and saved to the
+// client's DetectionStore. The store is only a cache, so its errors fall
+// back to probing rather than failing the registration.
+func (c *Client) detect(ctx context.Context, key, baseURL, probe string) (Detection, error) {
+	if c.detections != nil {
+		d, ok, err := c.detections.Load(ctx, key)
+		if err == nil && ok && d.BaseURL == baseURL {
+			return d, nil
+		}
+	}
+
+	hc := c.domainHTTPClient(key)
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	ft, err := detectForgeTypeAt(ctx, hc, probe)
+	if err != nil {
+		return Detection{}, err
+	}
+	d := Detection{
+		Type:       ft,
+		BaseURL:    baseURL,
+		Version:    detectVersion(ctx, hc, probe, ft),
+		DetectedAt: time.Now().UTC(),
+	}
+	if c.detections != nil {
+		_ = c.detections.Save(ctx, key, d)
+	}
+	return d, nil
+}
@@ -0,0 +1,221 @@
+package forges
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileDetectionStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache", "detections.json")
+	s := NewFileDetectionStore(path, time.Hour)
+
+	if _, ok, err := s.Load(ctx, "git.example.com"); err != nil || ok {
+		t.Fatalf("expected no detection before the first save, got ok=%v err=%v", ok, err)
+	}
+
+	d := Detection{Type: Gitea, BaseURL: "https://git.example.com", Version: "1.22.0", DetectedAt: time.Now()}
+	if err := s.Save(ctx, "git.example.com", d); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Save(ctx, "old.example.com", Detection{Type: GitLab, DetectedAt: time.Now().Add(-2 * time.Hour)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A second store on the same file sees what the first saved.
+	got, ok, err := NewFileDetectionStore(path, time.Hour).Load(ctx, "git.example.com")
+	if err != nil || !ok {
+		t.Fatalf("expected a stored detection, got ok=%v err=%v", ok, err)
+	}
+	assertEqual(t, "Type", string(Gitea), string(got.Type))
+	assertEqual(t, "BaseURL", d.BaseURL, got.BaseURL)
+	assertEqual(t, "Version", "1.22.0", got.Version)
+
+	if _, ok, _ := s.Load(ctx, "old.example.com"); ok {
+		t.Error("expected an expired detection to be ignored")
+	}
+	if _, ok, _ := NewFileDetectionStore(path, 0).Load(ctx, "old.example.com"); !ok {
+		t.Error("expected detections to be kept forever without a TTL")
+	}
+}
+
+// gitHubEnterpriseServer serves a GHES instance that identifies itself in
+// its response headers, counting detection probes.
+func gitHubEnterpriseServer(t *testing.T, probes *atomic.Int32) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("X-GitHub-Request-Id", "abc")
+	})
+	mux.HandleFunc("GET /api/v3/meta", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"installed_version": "3.14.2"})
+	})
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"full_name": "octocat/hello-world"})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClientRegisterDomainDetectionStore(t *testing.T) {
+	var probes atomic.Int32
+	srv := gitHubEnterpriseServer(t, &probes)
+	store := NewFileDetectionStore(filepath.Join(t.TempDir(), "detections.json"), time.Hour)
+
+	for range 2 {
+		c := NewClient(WithDetectionStore(store), WithInsecureHTTP("127.0.0.1"))
+		if err := c.RegisterDomain(context.Background(), srv.URL, ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEqual(t, "type", string(GitHub), string(c.forgeTypeFor(registrationKey(srv.URL))))
+	}
+	assertEqualInt(t, "probes", 1, int(probes.Load()))
+
+	d, ok, err := store.Load(context.Background(), registrationKey(srv.URL))
+	if err != nil || !ok {
+		t.Fatalf("expected a stored detection, got ok=%v err=%v", ok, err)
+	}
+	assertEqual(t, "BaseURL", srv.URL, d.BaseURL)
+	assertEqual(t, "Version", "3.14.2", d.Version)
+}
+
+func TestClientAutoRegister(t *testing.T) {
+	var probes atomic.Int32
+	srv := gitHubEnterpriseServer(t, &probes)
+	repoURL := srv.URL + "/octocat/hello-world"
+
+	c := NewClient(WithInsecureHTTP("127.0.0.1"))
+	if _, err := c.FetchRepository(context.Background(), repoURL); err == nil ||
+		!strings.Contains(err.Error(), "no forge registered") {
+		t.Fatalf("expected an unregistered domain error, got %v", err)
+	}
+
+	c = NewClient(WithAutoRegister(), WithInsecureHTTP("127.0.0.1"))
+	for range 2 {
+		repo, err := c.FetchRepository(context.Background(), repoURL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEqual(t, "FullName", "octocat/hello-world", repo.FullName)
+	}
+	assertEqualInt(t, "probes", 1, int(probes.Load()))
+
+	// Plain HTTP still has to be allowed explicitly.
+	c = NewClient(WithAutoRegister())
+	if _, err := c.FetchRepository(context.Background(), repoURL); err == nil {
+		t.Error("expected auto-registration to refuse plain HTTP")
+	}
+}
+
+func TestClientConcurrentAutoRegister(t *testing.T) {
+	var probes atomic.Int32
+	srv := gitHubEnterpriseServer(t, &probes)
+	c := NewClient(WithAutoRegister(), WithInsecureHTTP("127.0.0.1"))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for range 20 {
+		wg.Go(func() {
+			_, err := c.FetchRepository(context.Background(), srv.URL+"/octocat/hello-world")
+			errs <- err
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	assertEqualInt(t, "probes", 1, int(probes.Load()))
+}
+
+func TestClientConcurrentRegistration(t *testing.T) {
+	var probes atomic.Int32
+	srv := gitHubEnterpriseServer(t, &probes)
+	c := NewClient(WithInsecureHTTP("127.0.0.1"))
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if err := c.RegisterDomain(context.Background(), srv.URL, ""); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+		wg.Go(func() {
+			c.FetchRepository(context.Background(), srv.URL+"/octocat/hello-world")
+			c.Domains()
+		})
+	}
+	wg.Wait()
+	assertEqualInt(t, "probes", 1, int(probes.Load()))
+
+	key := registrationKey(srv.URL)
+	if !slices.Contains(c.Domains(), key) {
+		t.Errorf("expected %s in %v", key, c.Domains())
+	}
+	if !c.UnregisterDomain(srv.URL) {
+		t.Error("expected UnregisterDomain to report the registration")
+	}
+	if slices.Contains(c.Domains(), key) {
+		t.Errorf("expected %s to be unregistered", key)
+	}
+	if c.UnregisterDomain(srv.URL) {
+		t.Error("expected nothing left to unregister")
+	}
+	if _, err := c.FetchRepository(context.Background(), srv.URL+"/octocat/hello-world"); err == nil {
+		t.Error("expected no forge for an unregistered domain")
+	}
+}
+
+// countingTransport counts the requests sent through it.
+type countingTransport struct {
+	n atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.n.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClientRegisterDomainUsesHTTPClient(t *testing.T) {
+	var versions atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/version", func(w http.ResponseWriter, r *http.Request) {
+		versions.Add(1)
+		giteaVersionHandler(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	tr := &countingTransport{}
+
+	c := NewClient(WithHTTPClient(&http.Client{Transport: tr}), WithInsecureHTTP("127.0.0.1"))
+	if err := c.RegisterDomain(context.Background(), srv.URL, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tr.n.Load() == 0 {
+		t.Error("expected detection to go through the client's HTTP client")
+	}
+
+	// The detected version is handed to the backend, so the SDK needn't
+	// ask for it again.
+	f, ok := c.forges[registrationKey(srv.URL)].(*giteaForge)
+	if !ok {
+		t.Fatalf("expected a Gitea backend, got %T", c.forges[registrationKey(srv.URL)])
+	}
+	read := versions.Load()
+	assertEqual(t, "version", "1.21.0", f.serverVersion(context.Background(), nil))
+	assertEqualInt(t, "version reads", int(read), int(versions.Load()))
+}
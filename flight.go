@@ -0,0 +1,64 @@
+package forges
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// flightTimeout bounds a shared call, which outlives the caller that
+// started it so one caller giving up doesn't fail the others.
+const flightTimeout = 2 * time.Minute
+
+// flightGroup runs one call per key at a time. Callers that ask for a key
+// while its call is running wait for it and share its result, so many
+// goroutines meeting the same unknown domain probe it once.
+type flightGroup[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[T]
+}
+
+type flightCall[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+// do runs fn for key unless a call for key is already running, and waits
+// for the result. fn gets a context carrying ctx's values but not its
+// cancellation, limited to flightTimeout, so it keeps running for the
+// other waiters when the caller that started it gives up. Waiting stops
+// early if ctx ends.
+func (g *flightGroup[T]) do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	g.mu.Lock()
+	call, ok := g.calls[key]
+	if !ok {
+		if g.calls == nil {
+			g.calls = make(map[string]*flightCall[T])
+		}
+		call = &flightCall[T]{done: make(chan struct{})}
+		g.calls[key] = call
+		go g.run(context.WithoutCancel(ctx), key, call, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.val, call.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+func (g *flightGroup[T]) run(ctx context.Context, key string, call *flightCall[T], fn func(ctx context.Context) (T, error)) {
+	ctx, cancel := context.WithTimeout(ctx, flightTimeout)
+	defer cancel()
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.val, call.err = fn(ctx)
+}
@@ -0,0 +1,42 @@
+package forges
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFlightGroupFirstCallerCancels(t *testing.T) {
+	var g flightGroup[string]
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := g.do(first, "example.com", func(ctx context.Context) (string, error) {
+			close(started)
+			<-release
+			return "probed", ctx.Err()
+		})
+		firstErr <- err
+	}()
+	<-started
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the first caller to stop waiting, got %v", err)
+	}
+
+	// The call keeps running after its starter gave up, and a caller
+	// arriving meanwhile shares its result.
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+	v, err := g.do(context.Background(), "example.com", func(context.Context) (string, error) {
+		return "", errors.New("expected the running call to be shared")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "result", "probed", v)
+}
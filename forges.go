@@ -4,16 +4,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/git-pkgs/purl"
+	"golang.org/x/time/rate"
 )
 
 // ErrNotFound is returned when the requested repository does not exist.
 var ErrNotFound = errors.New("repository not found")
 
+// ErrNotSupported is returned when a forge has no API for the requested
+// operation or filter.
+var ErrNotSupported = errors.New("operation not supported by forge")
+
 // ErrOwnerNotFound is returned when the requested owner (org or user) does not exist.
 var ErrOwnerNotFound = errors.New("owner not found")
 
@@ -28,18 +37,90 @@ func (e *HTTPError) Error() string {
 	return fmt.Sprintf("forge: HTTP %d from %s", e.StatusCode, e.URL)
 }
 
-// Forge is the interface each forge backend implements.
+// Forge is the interface each forge backend implements. Backends may also
+// implement the optional interfaces below; the Client methods that need one
+// return ErrNotSupported for backends that don't.
 type Forge interface {
 	FetchRepository(ctx context.Context, owner, repo string) (*Repository, error)
 	FetchTags(ctx context.Context, owner, repo string) ([]Tag, error)
 	ListRepositories(ctx context.Context, owner string, opts ListOptions) ([]Repository, error)
 }
 
+// OptionsFetcher is implemented by forges that can enrich a repository as
+// FetchOptions asks.
+type OptionsFetcher interface {
+	FetchRepositoryWithOptions(ctx context.Context, owner, repo string, opts FetchOptions) (*Repository, error)
+}
+
+// IssueLister is implemented by forges that can list issues.
+type IssueLister interface {
+	ListIssues(ctx context.Context, owner, repo string, opts IssueListOptions) ([]Issue, error)
+}
+
+// PullRequestLister is implemented by forges that can list pull requests.
+type PullRequestLister interface {
+	ListPullRequests(ctx context.Context, owner, repo string, opts IssueListOptions) ([]PullRequest, error)
+}
+
+// IssueCounter is implemented by forges that can count open issues and
+// pull requests exactly.
+type IssueCounter interface {
+	FetchIssueCounts(ctx context.Context, owner, repo string) (*IssueCounts, error)
+}
+
+// Searcher is implemented by forges that can search repositories.
+type Searcher interface {
+	Search(ctx context.Context, query SearchQuery) ([]Repository, error)
+}
+
+// ForkLister is implemented by forges that can list a repository's forks.
+type ForkLister interface {
+	ListForks(ctx context.Context, owner, repo string) ([]Repository, error)
+}
+
+// ActivityFetcher is implemented by forges that can summarize commit
+// activity.
+type ActivityFetcher interface {
+	FetchActivity(ctx context.Context, owner, repo string, since time.Time) (*Activity, error)
+}
+
 // Client routes requests to the appropriate Forge based on the URL domain.
+//
+// Forges are registered under a key: the host, plus a port other than the
+// scheme's default, plus the path prefix for instances served below the
+// root, like example.com/gitlab or localhost:3000.
+//
+// A Client is safe for concurrent use, including registering domains while
+// other goroutines fetch.
 type Client struct {
+	// mu guards the registrations that RegisterDomain and UnregisterDomain
+	// change after construction: forges, tokens, domainTypes, baseURLs and
+	// registerErrs. The other maps are only written by options.
+	mu         sync.RWMutex
 	forges     map[string]Forge
 	tokens     map[string]string
 	httpClient *http.Client
+	// domainTypes records registrations made by options. The forges are
+	// built once all options have run, so option order doesn't matter.
+	domainTypes       map[string]ForgeType
+	aliases           map[string]string // alias host -> registered domain
+	baseURLs          map[string]string // domain -> instance base URL
+	apiBases          map[string]string // domain -> API base URL override
+	insecureHosts     map[string]bool   // hosts allowed over plain HTTP
+	gitHubApps        map[string]GitHubApp
+	credentials       CredentialProvider
+	tokenPools        map[string]*tokenPool
+	domainCredentials map[string]Credentials
+	rateLimits        map[string]*rate.Limiter
+	timeouts          map[string]time.Duration
+	detections        DetectionStore
+	autoRegister      bool
+	retryPolicy       *RetryPolicy
+	detectFlight      flightGroup[Detection]
+	autoFlight        flightGroup[Forge]
+	// registerErrs keeps why a registration failed, so lookups can say
+	// more than that the domain is unknown.
+	registerErrs map[string]error
 }
 
 // Option configures a Client.
@@ -48,7 +129,92 @@ type Option func(*Client)
 // WithToken sets the API token for the given domain.
 func WithToken(domain, token string) Option {
 	return func(c *Client) {
-		c.tokens[domain] = token
+		c.tokens[registrationKey(domain)] = token
+	}
+}
+
+// WithCredentials authenticates requests to domain with creds, for forges
+// and accounts that need more than a single API token: Bitbucket app
+// passwords, API tokens and OAuth consumers, GitLab CI job tokens, or Gitea
+// basic auth. Credentials that don't suit the forge registered for domain
+// make the registration fail, and ForgeFor reports why.
+func WithCredentials(domain string, creds Credentials) Option {
+	return func(c *Client) {
+		c.domainCredentials[registrationKey(domain)] = creds
+	}
+}
+
+// WithTokens spreads requests to domain over a pool of tokens, such as
+// several service accounts. Each request uses the token with the most rate
+// limit quota left, as reported by the forge's rate limit headers, taking
+// tokens in turn until their quota is known. A request rejected for
+// exceeding the rate limit is retried with the next token. Client.TokenQuotas
+// reports each token's state.
+func WithTokens(domain string, tokens []string) Option {
+	return func(c *Client) {
+		if len(tokens) > 0 {
+			c.tokenPools[registrationKey(domain)] = newTokenPool(tokens)
+		}
+	}
+}
+
+// WithCredentialProvider supplies tokens for domains that have none set
+// with WithToken or a registration option. The provider is asked on every
+// request, so rotated tokens are picked up without rebuilding the client.
+func WithCredentialProvider(p CredentialProvider) Option {
+	return func(c *Client) {
+		c.credentials = p
+	}
+}
+
+// WithRateLimit caps requests to domain at rps per second, in bursts of up
+// to burst; a burst of 0 allows rps requests at once. Requests over the
+// limit wait for their turn, or fail when their context ends first.
+func WithRateLimit(domain string, rps float64, burst int) Option {
+	return func(c *Client) {
+		if rps > 0 {
+			c.rateLimits[registrationKey(domain)] = newRateLimiter(rps, burst)
+		}
+	}
+}
+
+// WithTimeout bounds each HTTP request to domain, including reading the
+// response body, to d.
+func WithTimeout(domain string, d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.timeouts[registrationKey(domain)] = d
+		}
+	}
+}
+
+// WithDetectionStore makes RegisterDomain and auto-registration reuse
+// detection results kept in store, and save new ones there, instead of
+// probing each domain on every run.
+func WithDetectionStore(store DetectionStore) Option {
+	return func(c *Client) {
+		c.detections = store
+	}
+}
+
+// WithAutoRegister makes the Client detect and register domains it has no
+// forge for the first time a URL on them is used, as RegisterDomain would,
+// instead of failing. Tokens set for the domain with WithToken are kept.
+// Failed detections aren't remembered, so later calls probe again.
+func WithAutoRegister() Option {
+	return func(c *Client) {
+		c.autoRegister = true
+	}
+}
+
+// WithRetryPolicy retries requests to every forge that fail with a
+// connection error or a retryable status, such as a 503 in the middle of a
+// long listing. Each retry goes through the same authentication and rate
+// limits as the first attempt. A timeout set with WithTimeout covers all
+// attempts of a request.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = &p
 	}
 }
 
@@ -59,19 +225,94 @@ func WithHTTPClient(hc *http.Client) Option {
 	}
 }
 
-// WithGitea registers a self-hosted Gitea or Forgejo instance.
-func WithGitea(domain, token string) Option {
+// WithForge registers a domain running any forge type, including ones
+// added with RegisterForgeType. target is either a domain or the
+// instance's full base URL, as for WithGitea.
+func WithForge(target string, ft ForgeType, token string) Option {
 	return func(c *Client) {
-		c.tokens[domain] = token
-		c.forges[domain] = newGiteaForge("https://"+domain, token, c.httpClient)
+		c.register(target, token, ft)
 	}
 }
 
-// WithGitLab registers a self-hosted GitLab instance.
-func WithGitLab(domain, token string) Option {
+// WithGitHub registers a GitHub Enterprise Server instance. target is
+// either a domain or the instance's base URL, as for WithGitea; a trailing
+// /api/v3 is accepted and ignored. With an empty token the instance is
+// accessed anonymously.
+func WithGitHub(target, token string) Option {
 	return func(c *Client) {
-		c.tokens[domain] = token
-		c.forges[domain] = newGitLabForge("https://"+domain, token, c.httpClient)
+		target = strings.TrimSuffix(strings.TrimSuffix(target, "/"), "/api/v3")
+		c.register(target, token, GitHub)
+	}
+}
+
+// WithGitHubApp authenticates requests to the GitHub instance registered
+// for domain (github.com or a GitHub Enterprise Server) as a GitHub App
+// installation instead of with a personal token. Installation tokens are
+// minted on first use and refreshed before they expire.
+func WithGitHubApp(domain string, app GitHubApp) Option {
+	return func(c *Client) {
+		c.gitHubApps[registrationKey(domain)] = app
+	}
+}
+
+// WithGitea registers a self-hosted Gitea or Forgejo instance. target is
+// either a domain or the instance's full base URL, with scheme, port and
+// path prefix, like http://localhost:3000 or https://example.com/gitea/.
+func WithGitea(target, token string) Option {
+	return func(c *Client) {
+		c.register(target, token, Gitea)
+	}
+}
+
+// WithGitLab registers a self-hosted GitLab instance. target is either a
+// domain or the instance's full base URL, as for WithGitea.
+func WithGitLab(target, token string) Option {
+	return func(c *Client) {
+		c.register(target, token, GitLab)
+	}
+}
+
+// register records a registration made by an option.
+func (c *Client) register(target, token string, ft ForgeType) {
+	key, baseURL, err := parseRegistration(target)
+	if err != nil {
+		c.registerErrs[registrationKey(target)] = err
+		return
+	}
+	c.tokens[key] = token
+	c.domainTypes[key] = ft
+	c.baseURLs[key] = baseURL
+}
+
+// WithDomainAlias routes URLs on alias to the forge registered for
+// canonical. Use it for forges reachable under several hostnames, and for
+// SSH hosts that differ from the web host, so that
+// git@ssh.git.corp:team/repo resolves to the forge registered for
+// git.corp.example.com.
+func WithDomainAlias(alias, canonical string) Option {
+	return func(c *Client) {
+		c.aliases[registrationKey(alias)] = registrationKey(canonical)
+	}
+}
+
+// WithAPIBaseURL makes the forge registered for domain call its API at
+// baseURL instead of https://<domain>, for instances whose API is served
+// from another host. baseURL is the instance root, without /api/v4 or
+// similar. Bitbucket only exists as bitbucket.org and ignores it.
+func WithAPIBaseURL(domain, baseURL string) Option {
+	return func(c *Client) {
+		c.apiBases[registrationKey(domain)] = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithInsecureHTTP allows forges on the given hosts to be reached over
+// plain http:// base URLs. Without it, registrations with an http:// base
+// URL fail, so a typo can't send tokens over an unencrypted connection.
+func WithInsecureHTTP(hosts ...string) Option {
+	return func(c *Client) {
+		for _, h := range hosts {
+			c.insecureHosts[strings.ToLower(h)] = true
+		}
 	}
 }
 
@@ -79,76 +320,581 @@ func WithGitLab(domain, token string) Option {
 // the given options.
 func NewClient(opts ...Option) *Client {
 	c := &Client{
-		forges: make(map[string]Forge),
-		tokens: make(map[string]string),
+		forges:            make(map[string]Forge),
+		tokens:            make(map[string]string),
+		domainTypes:       make(map[string]ForgeType),
+		aliases:           make(map[string]string),
+		baseURLs:          make(map[string]string),
+		apiBases:          make(map[string]string),
+		insecureHosts:     make(map[string]bool),
+		gitHubApps:        make(map[string]GitHubApp),
+		tokenPools:        make(map[string]*tokenPool),
+		domainCredentials: make(map[string]Credentials),
+		rateLimits:        make(map[string]*rate.Limiter),
+		timeouts:          make(map[string]time.Duration),
+		registerErrs:      make(map[string]error),
 	}
 	for _, opt := range opts {
 		opt(c)
 	}
 
-	// Register defaults. Tokens may have been set via WithToken before this runs.
-	if _, ok := c.forges["github.com"]; !ok {
-		c.forges["github.com"] = newGitHubForge(c.tokens["github.com"], c.httpClient)
-	}
-	if _, ok := c.forges["gitlab.com"]; !ok {
-		c.forges["gitlab.com"] = newGitLabForge("https://gitlab.com", c.tokens["gitlab.com"], c.httpClient)
-	}
-	if _, ok := c.forges["codeberg.org"]; !ok {
-		c.forges["codeberg.org"] = newGiteaForge("https://codeberg.org", c.tokens["codeberg.org"], c.httpClient)
+	// Register defaults unless an option registered the domain itself.
+	for domain, ft := range knownForgeTypes {
+		if _, ok := c.domainTypes[domain]; !ok {
+			c.domainTypes[domain] = ft
+		}
 	}
-	if _, ok := c.forges["bitbucket.org"]; !ok {
-		c.forges["bitbucket.org"] = newBitbucketForge(c.tokens["bitbucket.org"], c.httpClient)
+	for domain, ft := range c.domainTypes {
+		f, err := c.newForge(ft, domain, c.tokens[domain], c.webBaseURL(domain), "")
+		if err != nil {
+			c.registerErrs[domain] = err
+			continue
+		}
+		c.forges[domain] = f
 	}
 	return c
 }
 
-// RegisterDomain detects the forge type for a domain and registers it.
-func (c *Client) RegisterDomain(ctx context.Context, domain, token string) error {
-	ft, err := DetectForgeType(ctx, domain)
+// parseRegistration splits a registration target, either a bare domain or
+// a base URL like http://localhost:3000 or https://example.com/gitlab/,
+// into the key the forge is registered under and the instance's base URL.
+// The key is the lowercased host, its port unless that is the scheme's
+// default, and any path prefix, so instances on two ports of one host are
+// kept apart. SSH URLs never carry the web port; route them to a forge on a
+// custom port with WithDomainAlias.
+func parseRegistration(target string) (key, baseURL string, err error) {
+	raw := strings.TrimSpace(target)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
 	if err != nil {
-		return fmt.Errorf("detecting forge type for %s: %w", domain, err)
+		return "", "", fmt.Errorf("invalid forge URL %q: %w", target, err)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return "", "", fmt.Errorf("unsupported forge URL scheme %q in %q", u.Scheme, target)
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return "", "", fmt.Errorf("forge URL has no host: %q", target)
 	}
-	c.tokens[domain] = token
-	baseURL := "https://" + domain
+	prefix := strings.TrimSuffix(u.Path, "/")
+	return joinPort(host, webPort(u)) + prefix, u.Scheme + "://" + strings.ToLower(u.Host) + prefix, nil
+}
+
+// webPort returns the port of an http(s) URL, or "" when it has none or it
+// is the scheme's default.
+func webPort(u *url.URL) string {
+	switch port := u.Port(); {
+	case u.Scheme == "https" && port == "443", u.Scheme == "http" && port == "80":
+		return ""
+	default:
+		return port
+	}
+}
+
+// joinPort appends port to host when there is one.
+func joinPort(host, port string) string {
+	if port == "" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// registrationKey returns the key a domain or base URL is registered
+// under. Targets that can't be parsed get a best guess at the key they were
+// meant for, so that a failed registration is reported for its domain.
+func registrationKey(target string) string {
+	key, _, err := parseRegistration(target)
+	if err == nil {
+		return key
+	}
+	key = strings.ToLower(strings.TrimSpace(target))
+	if _, rest, ok := strings.Cut(key, "://"); ok {
+		key = rest
+	}
+	host, prefix, _ := strings.Cut(key, "/")
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		host = h
+		if port != "" && strings.Trim(port, "0123456789") == "" {
+			host = joinPort(h, port)
+		}
+	}
+	if prefix = strings.Trim(prefix, "/"); prefix != "" {
+		return host + "/" + prefix
+	}
+	return host
+}
+
+// newForge builds the backend for a domain from its token, web base URL
+// and detected version, and the client's HTTP client and API base
+// overrides.
+func (c *Client) newForge(ft ForgeType, domain, token, webBase, version string) (Forge, error) {
+	baseURL := webBase
+	if base, ok := c.apiBases[domain]; ok {
+		baseURL = base
+	}
+	if err := c.checkScheme(baseURL); err != nil {
+		return nil, err
+	}
+
+	hc := c.domainHTTPClient(domain)
+	if app, ok := c.gitHubApps[domain]; ok && ft == GitHub {
+		var err error
+		hc, err = gitHubAppClient(hc, gitHubAPIURL(baseURL), app)
+		if err != nil {
+			return nil, err
+		}
+		token = ""
+	} else if creds, ok := c.domainCredentials[domain]; ok {
+		var err error
+		hc, err = authClient(hc, forgeAPIHost(ft, baseURL), ft, creds)
+		if err != nil {
+			return nil, fmt.Errorf("credentials for %s: %w", domain, err)
+		}
+		token = ""
+	} else if pool, ok := c.tokenPools[domain]; ok {
+		hc = tokenPoolClient(hc, forgeAPIHost(ft, baseURL), ft, pool)
+		token = ""
+	} else if token == "" && c.credentials != nil {
+		hc = credentialClient(hc, domain, forgeAPIHost(ft, baseURL), ft, c.credentials)
+	}
+	if c.retryPolicy != nil {
+		hc = retryClient(hc, *c.retryPolicy)
+	}
+
+	factory, ok := lookupForgeFactory(ft)
+	if !ok {
+		return nil, fmt.Errorf("unsupported forge type %q for %s", ft, domain)
+	}
+	return factory(ForgeConfig{
+		Domain:     domain,
+		BaseURL:    baseURL,
+		Token:      token,
+		HTTPClient: hc,
+		Version:    version,
+		Retrying:   c.retryPolicy != nil,
+	})
+}
+
+// forgeAPIHost returns the host a forge's API requests go to, which may
+// differ from its web host, as for api.github.com.
+func forgeAPIHost(ft ForgeType, baseURL string) string {
 	switch ft {
 	case GitHub:
-		c.forges[domain] = newGitHubForgeWithBase(baseURL, token, c.httpClient)
-	case GitLab:
-		c.forges[domain] = newGitLabForge(baseURL, token, c.httpClient)
-	case Gitea, Forgejo:
-		c.forges[domain] = newGiteaForge(baseURL, token, c.httpClient)
-	default:
-		return fmt.Errorf("unsupported forge type %q for %s", ft, domain)
+		baseURL = gitHubAPIURL(baseURL)
+	case Bitbucket:
+		baseURL = bitbucketAPI
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// wrapTransport returns a copy of hc, or of a default client when hc is
+// nil, whose transport is wrap applied to hc's, or to
+// http.DefaultTransport when hc has none.
+func wrapTransport(hc *http.Client, wrap func(http.RoundTripper) http.RoundTripper) *http.Client {
+	var client http.Client
+	if hc != nil {
+		client = *hc
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = wrap(base)
+	return &client
+}
+
+// webBaseURL returns the root of a registered forge's web UI.
+func (c *Client) webBaseURL(domain string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if base, ok := c.baseURLs[domain]; ok {
+		return base
+	}
+	return "https://" + domain
+}
+
+// checkScheme rejects plain HTTP base URLs unless their host was allowed
+// with WithInsecureHTTP.
+func (c *Client) checkScheme(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid forge URL %q: %w", baseURL, err)
+	}
+	if u.Scheme == "http" && !c.insecureHosts[strings.ToLower(u.Hostname())] {
+		return fmt.Errorf("plain HTTP to %s is not allowed; allow the host with WithInsecureHTTP", u.Host)
 	}
 	return nil
 }
 
+// RegisterDomain detects the forge type for a domain and registers it,
+// replacing any earlier registration. target is either a domain or the
+// instance's full base URL, as for WithGitea. Concurrent calls for the same
+// instance share one detection.
+func (c *Client) RegisterDomain(ctx context.Context, target, token string) error {
+	key, baseURL, err := parseRegistration(target)
+	if err != nil {
+		return err
+	}
+	probe := baseURL
+	if base, ok := c.apiBases[key]; ok {
+		probe = base
+	}
+	if err := c.checkScheme(probe); err != nil {
+		return err
+	}
+	d, err := c.detectFlight.do(ctx, key+" "+baseURL, func(ctx context.Context) (Detection, error) {
+		return c.detect(ctx, key, baseURL, probe)
+	})
+	if err != nil {
+		return fmt.Errorf("detecting forge type for %s: %w", target, err)
+	}
+	f, err := c.newForge(d.Type, key, token, baseURL, d.Version)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens[key] = token
+	c.baseURLs[key] = baseURL
+	c.forges[key] = f
+	c.domainTypes[key] = d.Type
+	delete(c.registerErrs, key)
+	return nil
+}
+
+// UnregisterDomain removes the forge registered for domain, which may be a
+// base URL or an alias, along with its token, so URLs on it are no longer
+// routed. It reports whether anything was registered.
+func (c *Client) UnregisterDomain(domain string) bool {
+	key := c.resolveDomain(domain)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.forges[key]
+	if _, failed := c.registerErrs[key]; failed {
+		ok = true
+	}
+	delete(c.forges, key)
+	delete(c.tokens, key)
+	delete(c.domainTypes, key)
+	delete(c.baseURLs, key)
+	delete(c.registerErrs, key)
+	return ok
+}
+
+// Domains lists the keys of the registered forges, sorted. Registrations
+// that failed are left out.
+func (c *Client) Domains() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	domains := make([]string, 0, len(c.forges))
+	for d := range c.forges {
+		domains = append(domains, d)
+	}
+	slices.Sort(domains)
+	return domains
+}
+
+// resolveDomain maps a domain, base URL or alias to the key of the forge
+// registered for it.
+func (c *Client) resolveDomain(domain string) string {
+	key := registrationKey(domain)
+	if canonical, ok := c.aliases[key]; ok {
+		return canonical
+	}
+	return key
+}
+
+// registered reports whether a registration, successful or not, exists
+// under key.
+func (c *Client) registered(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if _, ok := c.forges[key]; ok {
+		return true
+	}
+	_, ok := c.registerErrs[key]
+	return ok
+}
+
 func (c *Client) forgeFor(domain string) (Forge, error) {
-	f, ok := c.forges[domain]
+	key := c.resolveDomain(domain)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	f, ok := c.forges[key]
 	if !ok {
+		if err, failed := c.registerErrs[key]; failed {
+			return nil, fmt.Errorf("forge for domain %q: %w", domain, err)
+		}
 		return nil, fmt.Errorf("no forge registered for domain %q", domain)
 	}
 	return f, nil
 }
 
+// autoForgeFor is forgeFor, but first registers domains nothing is
+// registered for when auto-registration is on. target is what to detect:
+// the domain, or the origin of a URL on it, which keeps its scheme and port.
+func (c *Client) autoForgeFor(ctx context.Context, domain, target string) (Forge, error) {
+	f, err := c.forgeFor(domain)
+	if err == nil || !c.autoRegister {
+		return f, err
+	}
+	key := c.resolveDomain(domain)
+	if c.registered(key) {
+		return nil, err
+	}
+	return c.autoFlight.do(ctx, key, func(ctx context.Context) (Forge, error) {
+		// Another goroutine may have registered it since the lookup above.
+		if f, err := c.forgeFor(key); err == nil {
+			return f, nil
+		}
+		c.mu.RLock()
+		token := c.tokens[key]
+		c.mu.RUnlock()
+		if err := c.RegisterDomain(ctx, target, token); err != nil {
+			return nil, fmt.Errorf("auto-registering domain %q: %w", domain, err)
+		}
+		return c.forgeFor(key)
+	})
+}
+
+// urlOrigin returns the scheme, host and port of an http(s) URL, or
+// fallback for other URLs such as SSH ones.
+func urlOrigin(rawURL, fallback string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fallback
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// TokenQuotas reports the rate limit state of each token in the pool set
+// for domain with WithTokens, for monitoring. It returns nil when the domain
+// has no pool.
+func (c *Client) TokenQuotas(domain string) []TokenQuota {
+	pool, ok := c.tokenPools[c.resolveDomain(domain)]
+	if !ok {
+		return nil
+	}
+	return pool.snapshot()
+}
+
 // ForgeFor returns the Forge implementation registered for the given domain.
 func (c *Client) ForgeFor(domain string) (Forge, error) {
 	return c.forgeFor(domain)
 }
 
+// ParseRepoURL is like the package-level ParseRepoURL, but knows the
+// client's registrations: for forges registered under a path prefix, the
+// prefix is stripped from the path and included in the returned domain, and
+// aliases are replaced by the domain they stand for. On GitLab the owner is
+// the project's full namespace, subgroups included, as in RepoID.
+//
+// SSH URLs carry no path prefix, so to route git@example.com:team/repo to a
+// forge registered as https://example.com/gitlab, alias the host to it with
+// WithDomainAlias("example.com", "example.com/gitlab").
+func (c *Client) ParseRepoURL(rawURL string) (domain, owner, repo string, err error) {
+	host, port, path, err := splitRepoURL(rawURL)
+	if err != nil {
+		return "", "", "", err
+	}
+	domain, path = c.matchRegistration(joinPort(host, port), path)
+	if c.forgeTypeFor(domain) == GitLab {
+		owner, repo, err := splitGitLabPath(path)
+		return domain, owner, repo, err
+	}
+	return splitOwnerRepo(domain, path)
+}
+
+// matchRegistration finds the registration a URL's host and path belong
+// to, preferring the longest registered path prefix, and returns its key
+// and the path with the prefix removed.
+func (c *Client) matchRegistration(host, path string) (string, string) {
+	host = strings.ToLower(host)
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	// Leave at least owner/repo after the prefix.
+	for i := len(segs) - 2; i > 0; i-- {
+		key := host + "/" + strings.Join(segs[:i], "/")
+		if c.registered(key) {
+			return key, strings.Join(segs[i:], "/")
+		}
+	}
+	return c.resolveDomain(host), path
+}
+
+// URLBuilder returns a URLBuilder for a registered domain, using the URL
+// conventions of the forge registered for it.
+func (c *Client) URLBuilder(domain string) (*URLBuilder, error) {
+	f, err := c.forgeFor(domain)
+	if err != nil {
+		return nil, err
+	}
+	key := c.resolveDomain(domain)
+	b, err := NewURLBuilder(forgeTypeOf(f), key)
+	if err != nil {
+		return nil, err
+	}
+	b.BaseURL = c.webBaseURL(key)
+	return b, nil
+}
+
+// RepoID returns the canonical identity of a repository URL. It follows the
+// rules of ParseRepoID, additionally mapping domain aliases and path
+// prefixes as Client.ParseRepoURL does, and using the forge registered for
+// the domain to decide how owner and name are folded, so self-hosted GitLab
+// instances get GitLab path rules too.
+func (c *Client) RepoID(rawURL string) (RepoID, error) {
+	host, port, path, err := splitRepoURL(rawURL)
+	if err != nil {
+		return RepoID{}, err
+	}
+	domain, path := c.matchRegistration(joinPort(canonicalHost(host), port), path)
+	return repoIDFromPath(domain, path, c.forgeTypeFor(domain))
+}
+
+func (c *Client) forgeTypeFor(domain string) ForgeType {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if ft, ok := c.domainTypes[domain]; ok {
+		return ft
+	}
+	if f, ok := c.forges[domain]; ok {
+		if ft := forgeTypeOf(f); ft != Unknown {
+			return ft
+		}
+	}
+	return knownForgeType(domain)
+}
+
+// forgeTypeOf reports which forge software a backend talks to.
+func forgeTypeOf(f Forge) ForgeType {
+	switch f.(type) {
+	case *gitHubForge:
+		return GitHub
+	case *gitLabForge:
+		return GitLab
+	case *giteaForge:
+		return Gitea
+	case *bitbucketForge:
+		return Bitbucket
+	default:
+		return Unknown
+	}
+}
+
 // FetchRepository fetches normalized repository metadata from a URL string.
+// URLs on hosts with no registered forge are tried as Go import paths, so
+// golang.org/x/tools resolves to its forge mirror.
 func (c *Client) FetchRepository(ctx context.Context, repoURL string) (*Repository, error) {
-	domain, owner, repo, err := ParseRepoURL(repoURL)
+	f, owner, repo, err := c.forgeForURL(ctx, repoURL)
 	if err != nil {
-		return nil, err
+		var ok bool
+		if f, owner, repo, ok = c.forgeForGoImport(ctx, repoURL); !ok {
+			return nil, err
+		}
 	}
-	f, err := c.forgeFor(domain)
+	return f.FetchRepository(ctx, owner, repo)
+}
+
+// FetchRepositoryWithOptions fetches repository metadata from a URL string,
+// making the extra API calls selected by opts to fill in fields the forge's
+// repository endpoint leaves out. Backends that aren't an OptionsFetcher
+// fetch the plain repository; FieldsPopulated tells what they filled.
+func (c *Client) FetchRepositoryWithOptions(ctx context.Context, repoURL string, opts FetchOptions) (*Repository, error) {
+	f, owner, repo, err := c.forgeForURL(ctx, repoURL)
 	if err != nil {
 		return nil, err
 	}
+	if of, ok := f.(OptionsFetcher); ok {
+		return of.FetchRepositoryWithOptions(ctx, owner, repo, opts)
+	}
 	return f.FetchRepository(ctx, owner, repo)
 }
 
+// forgeForURL parses a repository URL and returns the Forge registered for
+// its domain along with the owner and repo.
+func (c *Client) forgeForURL(ctx context.Context, repoURL string) (Forge, string, string, error) {
+	domain, owner, repo, err := c.ParseRepoURL(repoURL)
+	if err != nil {
+		return nil, "", "", err
+	}
+	f, err := c.autoForgeFor(ctx, domain, urlOrigin(repoURL, domain))
+	if err != nil {
+		return nil, "", "", err
+	}
+	return f, owner, repo, nil
+}
+
+// forgeForGoImport resolves repoURL as a Go import path and returns the
+// registered forge hosting it, preferring the go-import repository root
+// over the go-source home page.
+func (c *Client) forgeForGoImport(ctx context.Context, repoURL string) (Forge, string, string, bool) {
+	importPath, ok := goImportPathFromURL(repoURL)
+	if !ok {
+		return nil, "", "", false
+	}
+	imp, err := resolveGoImportPath(ctx, c.httpClient, importPath)
+	if err != nil {
+		return nil, "", "", false
+	}
+	for _, u := range []string{imp.RepoURL, imp.HomeURL} {
+		domain, owner, repo, err := c.ParseRepoURL(u)
+		if err != nil {
+			continue
+		}
+		if f, err := c.forgeFor(domain); err == nil {
+			return f, owner, repo, true
+		}
+	}
+	return nil, "", "", false
+}
+
+// FetchRepositories fetches many repositories, keyed by RepoID so that each
+// is fetched once however many spellings of its URL repoURLs contains. URLs
+// that can't be parsed or fetched are reported in the error map under the
+// URL as given.
+func (c *Client) FetchRepositories(ctx context.Context, repoURLs []string) (map[RepoID]*Repository, map[string]error) {
+	repos := make(map[RepoID]*Repository)
+	errs := make(map[string]error)
+	failed := make(map[RepoID]error)
+
+	for _, u := range repoURLs {
+		id, err := c.RepoID(u)
+		if err != nil {
+			errs[u] = err
+			continue
+		}
+		if _, ok := repos[id]; ok {
+			continue
+		}
+		if err, ok := failed[id]; ok {
+			errs[u] = err
+			continue
+		}
+
+		var r *Repository
+		if f, ferr := c.forgeFor(id.Domain); ferr == nil {
+			r, err = f.FetchRepository(ctx, id.Owner, id.Name)
+		} else {
+			// Not a forge host, but it may be a Go import path.
+			r, err = c.FetchRepository(ctx, u)
+		}
+		if err != nil {
+			failed[id] = err
+			errs[u] = err
+			continue
+		}
+		repos[id] = r
+	}
+	return repos, errs
+}
+
 // FetchRepositoryFromPURL fetches repository metadata using a PURL's
 // repository_url qualifier.
 func (c *Client) FetchRepositoryFromPURL(ctx context.Context, p *purl.PURL) (*Repository, error) {
@@ -161,11 +907,7 @@ func (c *Client) FetchRepositoryFromPURL(ctx context.Context, p *purl.PURL) (*Re
 
 // FetchTags fetches git tags from a URL string.
 func (c *Client) FetchTags(ctx context.Context, repoURL string) ([]Tag, error) {
-	domain, owner, repo, err := ParseRepoURL(repoURL)
-	if err != nil {
-		return nil, err
-	}
-	f, err := c.forgeFor(domain)
+	f, owner, repo, err := c.forgeForURL(ctx, repoURL)
 	if err != nil {
 		return nil, err
 	}
@@ -174,13 +916,155 @@ func (c *Client) FetchTags(ctx context.Context, repoURL string) ([]Tag, error) {
 
 // ListRepositories lists all repositories for an owner on the given domain.
 func (c *Client) ListRepositories(ctx context.Context, domain, owner string, opts ListOptions) ([]Repository, error) {
-	f, err := c.forgeFor(domain)
+	f, err := c.autoForgeFor(ctx, domain, domain)
 	if err != nil {
 		return nil, err
 	}
 	return f.ListRepositories(ctx, owner, opts)
 }
 
+// ListIssues lists issues (never pull requests) for a repository URL.
+func (c *Client) ListIssues(ctx context.Context, repoURL string, opts IssueListOptions) ([]Issue, error) {
+	f, owner, repo, err := c.forgeForURL(ctx, repoURL)
+	if err != nil {
+		return nil, err
+	}
+	l, ok := f.(IssueLister)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return l.ListIssues(ctx, owner, repo, opts)
+}
+
+// ListPullRequests lists pull requests (merge requests on GitLab) for a
+// repository URL.
+func (c *Client) ListPullRequests(ctx context.Context, repoURL string, opts IssueListOptions) ([]PullRequest, error) {
+	f, owner, repo, err := c.forgeForURL(ctx, repoURL)
+	if err != nil {
+		return nil, err
+	}
+	l, ok := f.(PullRequestLister)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return l.ListPullRequests(ctx, owner, repo, opts)
+}
+
+// FetchIssueCounts fetches exact open issue and pull request counts for a
+// repository URL. Depending on the forge this costs one or two extra API calls.
+func (c *Client) FetchIssueCounts(ctx context.Context, repoURL string) (*IssueCounts, error) {
+	f, owner, repo, err := c.forgeForURL(ctx, repoURL)
+	if err != nil {
+		return nil, err
+	}
+	ic, ok := f.(IssueCounter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return ic.FetchIssueCounts(ctx, owner, repo)
+}
+
+// EnrichIssueCounts replaces repo.OpenIssuesCount with the exact number of
+// open issues (excluding pull requests) and sets repo.OpenPullRequestsCount.
+// It is opt-in because it needs extra API calls on most forges.
+func (c *Client) EnrichIssueCounts(ctx context.Context, repoURL string, repo *Repository) error {
+	counts, err := c.FetchIssueCounts(ctx, repoURL)
+	if err != nil {
+		return err
+	}
+	repo.OpenIssuesCount = counts.OpenIssues
+	repo.OpenPullRequestsCount = counts.OpenPullRequests
+	return nil
+}
+
+// FetchActivity summarizes commit activity on the default branch of a
+// repository URL since the given time.
+func (c *Client) FetchActivity(ctx context.Context, repoURL string, since time.Time) (*Activity, error) {
+	f, owner, repo, err := c.forgeForURL(ctx, repoURL)
+	if err != nil {
+		return nil, err
+	}
+	af, ok := f.(ActivityFetcher)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return af.FetchActivity(ctx, owner, repo, since)
+}
+
+// ListForks lists the direct forks of a repository URL.
+func (c *Client) ListForks(ctx context.Context, repoURL string) ([]Repository, error) {
+	f, owner, repo, err := c.forgeForURL(ctx, repoURL)
+	if err != nil {
+		return nil, err
+	}
+	l, ok := f.(ForkLister)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return l.ListForks(ctx, owner, repo)
+}
+
+// maxForkDepth bounds how far ResolveRoot follows SourceName links.
+const maxForkDepth = 32
+
+// ResolveRoot fetches the repository at repoURL and follows SourceName up
+// the fork network until it reaches a repository that isn't a fork. For a
+// repository that isn't a fork, the repository itself is returned.
+func (c *Client) ResolveRoot(ctx context.Context, repoURL string) (*Repository, error) {
+	f, owner, name, err := c.forgeForURL(ctx, repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for range maxForkDepth {
+		r, err := f.FetchRepository(ctx, owner, name)
+		if err != nil {
+			return nil, err
+		}
+		if r.SourceName == "" {
+			return r, nil
+		}
+		seen[strings.ToLower(owner+"/"+name)] = true
+		if seen[strings.ToLower(r.SourceName)] {
+			return nil, fmt.Errorf("fork cycle detected at %s", r.SourceName)
+		}
+		// GitLab parents may live in nested groups, so split on the last slash.
+		i := strings.LastIndex(r.SourceName, "/")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid source name %q", r.SourceName)
+		}
+		owner, name = r.SourceName[:i], r.SourceName[i+1:]
+	}
+	return nil, fmt.Errorf("fork chain for %s exceeds %d levels", repoURL, maxForkDepth)
+}
+
+// markForks flags repositories returned by a fork listing as forks of parent.
+// Fork listings often omit the parent, which is the repository being listed.
+func markForks(forks []Repository, parent string) []Repository {
+	for i := range forks {
+		forks[i].Fork = true
+		if forks[i].SourceName == "" {
+			forks[i].SourceName = parent
+		}
+	}
+	return forks
+}
+
+// Search runs a repository search against the forge registered for domain and
+// returns one page of results.
+func (c *Client) Search(ctx context.Context, domain string, query SearchQuery) ([]Repository, error) {
+	f, err := c.autoForgeFor(ctx, domain, domain)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := f.(Searcher)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return s.Search(ctx, query)
+}
+
 // FilterRepos applies archived and fork filters to a slice of repositories.
 func FilterRepos(repos []Repository, opts ListOptions) []Repository {
 	n := 0
@@ -211,55 +1095,55 @@ func FilterRepos(repos []Repository, opts ListOptions) []Repository {
 	return repos[:n]
 }
 
-// FetchTagsFromPURL fetches git tags using a PURL's repository_url qualifier.
-func (c *Client) FetchTagsFromPURL(ctx context.Context, p *purl.PURL) ([]Tag, error) {
-	repoURL := p.RepositoryURL()
-	if repoURL == "" {
-		return nil, fmt.Errorf("PURL has no repository_url qualifier")
+// FilterSearchResults applies the topic, language, star and archived/fork
+// criteria of a SearchQuery to a slice of repositories. Backends use it for
+// criteria their search API can't express.
+func FilterSearchResults(repos []Repository, query SearchQuery) []Repository {
+	repos = FilterRepos(repos, ListOptions{Archived: query.Archived, Forks: query.Forks})
+	n := 0
+	for _, r := range repos {
+		if r.StargazersCount < query.MinStars {
+			continue
+		}
+		if query.Language != "" && !strings.EqualFold(r.Language, query.Language) {
+			continue
+		}
+		if query.Topic != "" && !hasTopic(r.Topics, query.Topic) {
+			continue
+		}
+		repos[n] = r
+		n++
 	}
-	return c.FetchTags(ctx, repoURL)
+	return repos[:n]
 }
 
-// ParseRepoURL extracts the domain, owner, and repo from a repository URL.
-// It handles https://, schemeless, and git@host:owner/repo SSH URLs, and
-// strips .git suffixes and extra path segments.
-func ParseRepoURL(rawURL string) (domain, owner, repo string, err error) {
-	rawURL = strings.TrimSpace(rawURL)
-	if rawURL == "" {
-		return "", "", "", fmt.Errorf("empty URL")
-	}
-
-	// Handle git@ SSH URLs: git@github.com:owner/repo.git
-	if strings.HasPrefix(rawURL, "git@") {
-		rawURL = strings.TrimPrefix(rawURL, "git@")
-		colonIdx := strings.Index(rawURL, ":")
-		if colonIdx < 0 {
-			return "", "", "", fmt.Errorf("invalid SSH URL: missing colon")
+func hasTopic(topics []string, topic string) bool {
+	for _, t := range topics {
+		if strings.EqualFold(t, topic) {
+			return true
 		}
-		domain = rawURL[:colonIdx]
-		path := rawURL[colonIdx+1:]
-		return splitOwnerRepo(domain, path)
 	}
+	return false
+}
 
-	// Add scheme if missing
-	if !strings.Contains(rawURL, "://") {
-		rawURL = "https://" + rawURL
-	}
+// reachedLimit reports whether a list operation has collected enough results.
+func reachedLimit(n, limit int) bool {
+	return limit > 0 && n >= limit
+}
 
-	u, err := url.Parse(rawURL)
-	if err != nil {
-		return "", "", "", fmt.Errorf("invalid URL: %w", err)
+// truncate trims a result slice to limit when limit is positive.
+func truncate[T any](items []T, limit int) []T {
+	if limit > 0 && len(items) > limit {
+		return items[:limit]
 	}
-	domain = u.Hostname()
-	return splitOwnerRepo(domain, u.Path)
+	return items
 }
 
-func splitOwnerRepo(domain, path string) (string, string, string, error) {
-	path = strings.TrimSuffix(path, ".git")
-	path = strings.Trim(path, "/")
-	parts := strings.Split(path, "/")
-	if len(parts) < 2 {
-		return "", "", "", fmt.Errorf("URL path must contain owner/repo, got %q", path)
+// FetchTagsFromPURL fetches git tags using a PURL's repository_url qualifier.
+func (c *Client) FetchTagsFromPURL(ctx context.Context, p *purl.PURL) ([]Tag, error) {
+	repoURL := p.RepositoryURL()
+	if repoURL == "" {
+		return nil, fmt.Errorf("PURL has no repository_url qualifier")
 	}
-	return domain, parts[0], parts[1], nil
+	return c.FetchTags(ctx, repoURL)
 }
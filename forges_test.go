@@ -2,9 +2,11 @@ package forges
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -90,10 +92,70 @@ func TestParseRepoURL(t *testing.T) {
 			input:  "https://bitbucket.org/atlassian/stash-example-plugin",
 			domain: "bitbucket.org", owner: "atlassian", repo: "stash-example-plugin",
 		},
+		{
+			input:  "ssh://git@gitlab.example.com:2222/group/repo.git",
+			domain: "gitlab.example.com", owner: "group", repo: "repo",
+		},
+		{
+			input:  "ssh://gitlab.example.com/group/repo",
+			domain: "gitlab.example.com", owner: "group", repo: "repo",
+		},
+		{
+			input:  "git+https://github.com/o/r",
+			domain: "github.com", owner: "o", repo: "r",
+		},
+		{
+			input:  "git+ssh://git@github.com/o/r.git",
+			domain: "github.com", owner: "o", repo: "r",
+		},
+		{
+			input:  "git://git.example.org/o/r",
+			domain: "git.example.org", owner: "o", repo: "r",
+		},
+		{
+			input:  "http://git.example.org:3000/o/r",
+			domain: "git.example.org", owner: "o", repo: "r",
+		},
+		{
+			input:  "git.example.org:3000/o/r",
+			domain: "git.example.org", owner: "o", repo: "r",
+		},
+		{
+			input:  "deploy@ghes.example.com:org/repo.git",
+			domain: "ghes.example.com", owner: "org", repo: "repo",
+		},
+		{
+			input:  "ghes.example.com:org/repo",
+			domain: "ghes.example.com", owner: "org", repo: "repo",
+		},
+		{
+			input:  "github:octocat/hello-world",
+			domain: "github.com", owner: "octocat", repo: "hello-world",
+		},
+		{
+			input:  "github:octocat/hello-world#v1.0.0",
+			domain: "github.com", owner: "octocat", repo: "hello-world",
+		},
+		{
+			input:  "gitlab:group/project",
+			domain: "gitlab.com", owner: "group", repo: "project",
+		},
+		{
+			input:  "bitbucket:atlassian/stash-example-plugin",
+			domain: "bitbucket.org", owner: "atlassian", repo: "stash-example-plugin",
+		},
 		{
 			input:   "",
 			wantErr: true,
 		},
+		{
+			input:   "ftp://github.com/o/r",
+			wantErr: true,
+		},
+		{
+			input:   "github:just-owner",
+			wantErr: true,
+		},
 		{
 			input:   "https://github.com/just-owner",
 			wantErr: true,
@@ -142,6 +204,249 @@ func TestClientRouting(t *testing.T) {
 	}
 }
 
+func TestClientDomainAliases(t *testing.T) {
+	mock := &mockForge{repo: &Repository{FullName: "team/repo"}}
+	c := &Client{
+		forges:  map[string]Forge{"git.corp.example.com": mock},
+		tokens:  make(map[string]string),
+		aliases: make(map[string]string),
+	}
+	WithDomainAlias("git.corp", "git.corp.example.com")(c)
+	WithDomainAlias("SSH.git.corp", "git.corp.example.com")(c)
+
+	for _, u := range []string{
+		"https://git.corp/team/repo",
+		"git@ssh.git.corp:team/repo.git",
+		"ssh://git@ssh.git.corp:2222/team/repo.git",
+	} {
+		repo, err := c.FetchRepository(context.Background(), u)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", u, err)
+		}
+		assertEqual(t, u, "team/repo", repo.FullName)
+	}
+
+	id, err := c.RepoID("git@ssh.git.corp:team/repo.git")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "RepoID", "https://git.corp.example.com/team/repo", id.URL())
+}
+
+func TestClientAPIBaseURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/team%2Frepo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":                  1,
+			"path_with_namespace": "team/repo",
+			"name":                "repo",
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// The override is given before the registration it applies to.
+	c := NewClient(
+		WithAPIBaseURL("gitlab.corp.example.com", srv.URL+"/"),
+		WithGitLab("gitlab.corp.example.com", ""),
+		WithDomainAlias("ssh.gitlab.corp.example.com", "gitlab.corp.example.com"),
+		WithInsecureHTTP("127.0.0.1"),
+	)
+
+	repo, err := c.FetchRepository(context.Background(), "git@ssh.gitlab.corp.example.com:team/repo.git")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "FullName", "team/repo", repo.FullName)
+
+	b, err := c.URLBuilder("ssh.gitlab.corp.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "WebURL", "https://gitlab.corp.example.com/team/repo", b.WebURL("team", "repo"))
+}
+
+func TestParseRegistration(t *testing.T) {
+	tests := []struct {
+		target  string
+		key     string
+		baseURL string
+	}{
+		{"gitlab.example.com", "gitlab.example.com", "https://gitlab.example.com"},
+		{"Git.Example.com", "git.example.com", "https://git.example.com"},
+		{"https://example.com/gitlab/", "example.com/gitlab", "https://example.com/gitlab"},
+		{"http://localhost:3000", "localhost:3000", "http://localhost:3000"},
+		{"localhost:3000/gitea", "localhost:3000/gitea", "https://localhost:3000/gitea"},
+		{"https://git.example.com:443", "git.example.com", "https://git.example.com:443"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			key, baseURL, err := parseRegistration(tt.target)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertEqual(t, "key", tt.key, key)
+			assertEqual(t, "baseURL", tt.baseURL, baseURL)
+		})
+	}
+
+	for _, target := range []string{"ftp://example.com", "https:///path"} {
+		if _, _, err := parseRegistration(target); err == nil {
+			t.Errorf("%s: expected error", target)
+		}
+	}
+}
+
+func TestClientRegistrationPorts(t *testing.T) {
+	servers := make([]*httptest.Server, 2)
+	for i := range servers {
+		name := fmt.Sprintf("team/repo-%d", i)
+		servers[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]any{"full_name": name})
+		}))
+		defer servers[i].Close()
+	}
+
+	c := NewClient(
+		WithGitHub(servers[0].URL, ""),
+		WithGitHub(servers[1].URL, ""),
+		WithInsecureHTTP("127.0.0.1"),
+	)
+	for i, srv := range servers {
+		repo, err := c.FetchRepository(context.Background(), srv.URL+"/team/repo")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEqual(t, "FullName", fmt.Sprintf("team/repo-%d", i), repo.FullName)
+	}
+}
+
+func TestClientRegistrationParseError(t *testing.T) {
+	c := NewClient(WithGitea("ftp://git.example.com", ""))
+	_, err := c.FetchRepository(context.Background(), "https://git.example.com/team/repo")
+	if err == nil || !strings.Contains(err.Error(), "unsupported forge URL scheme") {
+		t.Errorf("expected the registration error, got %v", err)
+	}
+	if _, err := c.ForgeFor("git.example.com"); err == nil || !strings.Contains(err.Error(), "ftp") {
+		t.Errorf("expected the registration error from ForgeFor, got %v", err)
+	}
+}
+
+func TestClientPathPrefix(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gitea/api/v1/version", giteaVersionHandler)
+	mux.HandleFunc("GET /gitea/api/v1/repos/team/repo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"full_name": "team/repo",
+			"name":      "repo",
+			"owner":     map[string]any{"login": "team"},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := NewClient(
+		WithGitea(srv.URL+"/gitea/", ""),
+		WithInsecureHTTP("127.0.0.1"),
+	)
+
+	domain, owner, repo, err := c.ParseRepoURL(srv.URL + "/gitea/team/repo/src/branch/main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "domain", strings.TrimPrefix(srv.URL, "http://")+"/gitea", domain)
+	assertEqual(t, "owner", "team", owner)
+	assertEqual(t, "repo", "repo", repo)
+
+	r, err := c.FetchRepository(context.Background(), srv.URL+"/gitea/team/repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "FullName", "team/repo", r.FullName)
+
+	id, err := c.RepoID(srv.URL + "/gitea/Team/Repo.git")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "RepoID", strings.TrimPrefix(srv.URL, "http://")+"/gitea/team/repo", id.String())
+
+	b, err := c.URLBuilder(srv.URL + "/gitea")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "WebURL", srv.URL+"/gitea/team/repo", b.WebURL("team", "repo"))
+	assertEqual(t, "SSHCloneURL", "git@127.0.0.1:team/repo.git", b.SSHCloneURL("team", "repo"))
+
+	// Without the prefix the first segment is the owner, on an
+	// unregistered host.
+	if _, err := c.FetchRepository(context.Background(), srv.URL+"/team/repo"); err == nil {
+		t.Error("expected error for URL outside the path prefix")
+	}
+}
+
+func TestClientWithGitHub(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"full_name": "octocat/hello-world"})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := NewClient(
+		WithGitHub(srv.URL+"/api/v3/", ""),
+		WithInsecureHTTP("127.0.0.1"),
+	)
+
+	repo, err := c.FetchRepository(context.Background(), srv.URL+"/octocat/hello-world")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "FullName", "octocat/hello-world", repo.FullName)
+
+	b, err := c.URLBuilder(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "TagURL", srv.URL+"/octocat/hello-world/releases/tag/v1", b.TagURL("octocat", "hello-world", "v1"))
+}
+
+func TestClientInsecureHTTPNotAllowed(t *testing.T) {
+	c := NewClient(WithGitLab("http://localhost:8080", ""))
+
+	_, err := c.ForgeFor("localhost:8080")
+	if err == nil {
+		t.Fatal("expected error for plain HTTP registration")
+	}
+	if !strings.Contains(err.Error(), "WithInsecureHTTP") {
+		t.Errorf("error should mention WithInsecureHTTP, got %v", err)
+	}
+
+	err = c.RegisterDomain(context.Background(), "http://localhost:8080", "")
+	if err == nil {
+		t.Error("expected RegisterDomain to refuse plain HTTP")
+	}
+}
+
+func TestClientRegisterDomainBaseURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gitlab", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Gitlab-Meta", `{"cors":"abc"}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := NewClient(WithInsecureHTTP("127.0.0.1"))
+	if err := c.RegisterDomain(context.Background(), srv.URL+"/gitlab", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f, err := c.ForgeFor(srv.URL + "/gitlab")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "type", string(GitLab), string(forgeTypeOf(f)))
+}
+
 func TestClientFetchRepositoryRoutes(t *testing.T) {
 	// Create a mock forge that records calls
 	mock := &mockForge{
@@ -182,6 +487,23 @@ func TestClientFetchTagsRoutes(t *testing.T) {
 	}
 }
 
+func TestClientEnrichIssueCounts(t *testing.T) {
+	mock := &mockForge{
+		counts: &IssueCounts{OpenIssues: 4, OpenPullRequests: 6},
+	}
+	c := &Client{
+		forges: map[string]Forge{"example.com": mock},
+		tokens: make(map[string]string),
+	}
+
+	repo := &Repository{FullName: "test/repo", OpenIssuesCount: 10}
+	if err := c.EnrichIssueCounts(context.Background(), "https://example.com/test/repo", repo); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqualInt(t, "OpenIssuesCount", 4, repo.OpenIssuesCount)
+	assertEqualInt(t, "OpenPullRequestsCount", 6, repo.OpenPullRequestsCount)
+}
+
 // Detection tests
 
 func TestDetectForgeTypeHeaders(t *testing.T) {
@@ -205,7 +527,7 @@ func TestDetectForgeTypeHeaders(t *testing.T) {
 			defer srv.Close()
 
 			// We need to override the URL scheme, so test detectFromHeaders directly
-			ft, err := detectFromHeaders(context.Background(), srv.URL)
+			ft, err := detectFromHeaders(context.Background(), http.DefaultClient, srv.URL)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -228,7 +550,7 @@ func TestDetectForgeTypeGiteaAPI(t *testing.T) {
 	srv := httptest.NewServer(mux)
 	defer srv.Close()
 
-	ft, err := detectFromAPI(context.Background(), srv.URL)
+	ft, err := detectFromAPI(context.Background(), http.DefaultClient, srv.URL)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -249,7 +571,7 @@ func TestDetectForgeTypeForgejoAPI(t *testing.T) {
 	srv := httptest.NewServer(mux)
 	defer srv.Close()
 
-	ft, err := detectFromAPI(context.Background(), srv.URL)
+	ft, err := detectFromAPI(context.Background(), http.DefaultClient, srv.URL)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -273,7 +595,7 @@ func TestDetectForgeTypeGitLabAPI(t *testing.T) {
 	srv := httptest.NewServer(mux)
 	defer srv.Close()
 
-	ft, err := detectFromAPI(context.Background(), srv.URL)
+	ft, err := detectFromAPI(context.Background(), http.DefaultClient, srv.URL)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -300,7 +622,7 @@ func TestDetectForgeTypeGitHubAPI(t *testing.T) {
 	srv := httptest.NewServer(mux)
 	defer srv.Close()
 
-	ft, err := detectFromAPI(context.Background(), srv.URL)
+	ft, err := detectFromAPI(context.Background(), http.DefaultClient, srv.URL)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -369,22 +691,238 @@ func TestFilterRepos(t *testing.T) {
 	}
 }
 
+func TestFilterSearchResults(t *testing.T) {
+	repos := []Repository{
+		{FullName: "a/go-popular", Language: "Go", StargazersCount: 500, Topics: []string{"cli"}},
+		{FullName: "a/go-small", Language: "Go", StargazersCount: 5, Topics: []string{"cli"}},
+		{FullName: "a/rust-popular", Language: "Rust", StargazersCount: 900},
+		{FullName: "a/go-archived", Language: "go", StargazersCount: 700, Archived: true, Topics: []string{"CLI"}},
+	}
+
+	tests := []struct {
+		name  string
+		query SearchQuery
+		want  []string
+	}{
+		{"no criteria", SearchQuery{}, []string{"a/go-popular", "a/go-small", "a/rust-popular", "a/go-archived"}},
+		{"language is case-insensitive", SearchQuery{Language: "GO"}, []string{"a/go-popular", "a/go-small", "a/go-archived"}},
+		{"min stars", SearchQuery{MinStars: 100}, []string{"a/go-popular", "a/rust-popular", "a/go-archived"}},
+		{"topic and not archived", SearchQuery{Topic: "cli", Archived: ArchivedExclude}, []string{"a/go-popular", "a/go-small"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := make([]Repository, len(repos))
+			copy(input, repos)
+			var names []string
+			for _, r := range FilterSearchResults(input, tt.query) {
+				names = append(names, r.FullName)
+			}
+			assertSliceEqual(t, "repos", tt.want, names)
+		})
+	}
+}
+
+func TestClientSearchRoutes(t *testing.T) {
+	mock := &mockForge{
+		repos: []Repository{{FullName: "org/match"}},
+	}
+	c := &Client{
+		forges: map[string]Forge{"example.com": mock},
+		tokens: make(map[string]string),
+	}
+
+	repos, err := c.Search(context.Background(), "example.com", SearchQuery{Text: "parser"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repos) != 1 {
+		t.Fatalf("expected 1 repo, got %d", len(repos))
+	}
+	assertEqual(t, "query.Text", "parser", mock.lastQuery.Text)
+
+	if _, err := c.Search(context.Background(), "unknown.example", SearchQuery{}); err == nil {
+		t.Error("expected error for unregistered domain")
+	}
+}
+
+// basicForge implements Forge and none of the optional interfaces, as a
+// third-party backend might.
+type basicForge struct {
+	repo *Repository
+}
+
+func (b *basicForge) FetchRepository(context.Context, string, string) (*Repository, error) {
+	return b.repo, nil
+}
+
+func (b *basicForge) FetchTags(context.Context, string, string) ([]Tag, error) {
+	return nil, nil
+}
+
+func (b *basicForge) ListRepositories(context.Context, string, ListOptions) ([]Repository, error) {
+	return nil, nil
+}
+
+func TestClientOptionalInterfaces(t *testing.T) {
+	c := &Client{
+		forges: map[string]Forge{"example.com": &basicForge{repo: &Repository{FullName: "team/repo"}}},
+		tokens: make(map[string]string),
+	}
+	ctx := context.Background()
+	repoURL := "https://example.com/team/repo"
+
+	calls := map[string]func() error{
+		"ListIssues": func() error {
+			_, err := c.ListIssues(ctx, repoURL, IssueListOptions{})
+			return err
+		},
+		"ListPullRequests": func() error {
+			_, err := c.ListPullRequests(ctx, repoURL, IssueListOptions{})
+			return err
+		},
+		"FetchIssueCounts": func() error {
+			_, err := c.FetchIssueCounts(ctx, repoURL)
+			return err
+		},
+		"FetchActivity": func() error {
+			_, err := c.FetchActivity(ctx, repoURL, time.Now())
+			return err
+		},
+		"ListForks": func() error {
+			_, err := c.ListForks(ctx, repoURL)
+			return err
+		},
+		"Search": func() error {
+			_, err := c.Search(ctx, "example.com", SearchQuery{Text: "parser"})
+			return err
+		},
+	}
+	for name, call := range calls {
+		if err := call(); err != ErrNotSupported {
+			t.Errorf("%s: expected ErrNotSupported, got %v", name, err)
+		}
+	}
+
+	// Enrichment falls back to the plain fetch.
+	repo, err := c.FetchRepositoryWithOptions(ctx, repoURL, FetchOptions{Extended: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "FullName", "team/repo", repo.FullName)
+}
+
+func TestBuiltinForgesImplementOptionalInterfaces(t *testing.T) {
+	for _, f := range []Forge{
+		newGitHubForge("", nil),
+		newGitLabForge("https://gitlab.com", "", nil),
+		newGiteaForge("https://codeberg.org", "", nil),
+		newBitbucketForge("", nil),
+	} {
+		if _, ok := f.(OptionsFetcher); !ok {
+			t.Errorf("%T is not an OptionsFetcher", f)
+		}
+		if _, ok := f.(IssueLister); !ok {
+			t.Errorf("%T is not an IssueLister", f)
+		}
+		if _, ok := f.(PullRequestLister); !ok {
+			t.Errorf("%T is not a PullRequestLister", f)
+		}
+		if _, ok := f.(IssueCounter); !ok {
+			t.Errorf("%T is not an IssueCounter", f)
+		}
+		if _, ok := f.(Searcher); !ok {
+			t.Errorf("%T is not a Searcher", f)
+		}
+		if _, ok := f.(ForkLister); !ok {
+			t.Errorf("%T is not a ForkLister", f)
+		}
+		if _, ok := f.(ActivityFetcher); !ok {
+			t.Errorf("%T is not an ActivityFetcher", f)
+		}
+	}
+}
+
+func TestClientResolveRoot(t *testing.T) {
+	mock := &mockForge{
+		repoMap: map[string]*Repository{
+			"me/lib":        {FullName: "me/lib", SourceName: "team/lib"},
+			"team/lib":      {FullName: "team/lib", SourceName: "group/sub/lib"},
+			"group/sub/lib": {FullName: "group/sub/lib"},
+			"loop/a":        {FullName: "loop/a", SourceName: "loop/b"},
+			"loop/b":        {FullName: "loop/b", SourceName: "loop/a"},
+			"dangling/fork": {FullName: "dangling/fork", SourceName: "gone/upstream"},
+		},
+	}
+	c := &Client{
+		forges: map[string]Forge{"example.com": mock},
+		tokens: make(map[string]string),
+	}
+
+	root, err := c.ResolveRoot(context.Background(), "https://example.com/me/lib")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "root", "group/sub/lib", root.FullName)
+
+	_, err = c.ResolveRoot(context.Background(), "https://example.com/nobody/none")
+	if err == nil {
+		t.Error("expected error for unknown repo")
+	}
+
+	if _, err := c.ResolveRoot(context.Background(), "https://example.com/loop/a"); err == nil {
+		t.Error("expected error for fork cycle")
+	}
+
+	if _, err := c.ResolveRoot(context.Background(), "https://example.com/dangling/fork"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for missing parent, got %v", err)
+	}
+}
+
+func TestMarkForks(t *testing.T) {
+	forks := markForks([]Repository{
+		{FullName: "a/lib"},
+		{FullName: "b/lib", SourceName: "other/lib"},
+	}, "up/lib")
+	assertEqualBool(t, "forks[0].Fork", true, forks[0].Fork)
+	assertEqual(t, "forks[0].SourceName", "up/lib", forks[0].SourceName)
+	assertEqual(t, "forks[1].SourceName", "other/lib", forks[1].SourceName)
+}
+
 // Mock forge for routing tests
 
 type mockForge struct {
 	repo      *Repository
+	repoMap   map[string]*Repository // keyed by owner/repo, checked before repo
 	repos     []Repository
 	tags      []Tag
+	issues    []Issue
+	pulls     []PullRequest
+	counts    *IssueCounts
+	lastQuery SearchQuery
 	lastOwner string
 	lastRepo  string
+	fetches   int
 }
 
 func (m *mockForge) FetchRepository(_ context.Context, owner, repo string) (*Repository, error) {
+	m.fetches++
 	m.lastOwner = owner
 	m.lastRepo = repo
+	if m.repoMap != nil {
+		r, ok := m.repoMap[owner+"/"+repo]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		return r, nil
+	}
 	return m.repo, nil
 }
 
+func (m *mockForge) FetchRepositoryWithOptions(ctx context.Context, owner, repo string, _ FetchOptions) (*Repository, error) {
+	return m.FetchRepository(ctx, owner, repo)
+}
+
 func (m *mockForge) FetchTags(_ context.Context, owner, repo string) ([]Tag, error) {
 	m.lastOwner = owner
 	m.lastRepo = repo
@@ -395,3 +933,38 @@ func (m *mockForge) ListRepositories(_ context.Context, owner string, opts ListO
 	m.lastOwner = owner
 	return m.repos, nil
 }
+
+func (m *mockForge) ListIssues(_ context.Context, owner, repo string, opts IssueListOptions) ([]Issue, error) {
+	m.lastOwner = owner
+	m.lastRepo = repo
+	return m.issues, nil
+}
+
+func (m *mockForge) ListPullRequests(_ context.Context, owner, repo string, opts IssueListOptions) ([]PullRequest, error) {
+	m.lastOwner = owner
+	m.lastRepo = repo
+	return m.pulls, nil
+}
+
+func (m *mockForge) FetchIssueCounts(_ context.Context, owner, repo string) (*IssueCounts, error) {
+	m.lastOwner = owner
+	m.lastRepo = repo
+	return m.counts, nil
+}
+
+func (m *mockForge) Search(_ context.Context, query SearchQuery) ([]Repository, error) {
+	m.lastQuery = query
+	return m.repos, nil
+}
+
+func (m *mockForge) ListForks(_ context.Context, owner, repo string) ([]Repository, error) {
+	m.lastOwner = owner
+	m.lastRepo = repo
+	return m.repos, nil
+}
+
+func (m *mockForge) FetchActivity(_ context.Context, owner, repo string, since time.Time) (*Activity, error) {
+	m.lastOwner = owner
+	m.lastRepo = repo
+	return &Activity{Since: since}, nil
+}
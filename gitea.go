@@ -2,55 +2,155 @@ package forges
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"code.gitea.io/sdk/gitea"
 )
 
 type giteaForge struct {
-	client *gitea.Client
+	// The SDK doesn't wrap every endpoint, so keep what's needed to call
+	// the rest directly as well as to build SDK clients.
+	baseURL    string
+	token      string
+	httpClient *http.Client
+
+	mu           sync.Mutex
+	version      string // server version, "" if it couldn't be read
+	versionKnown bool
+	versionRead  flightGroup[string]
 }
 
 func newGiteaForge(baseURL, token string, hc *http.Client) *giteaForge {
-	opts := []gitea.ClientOption{}
-	if token != "" {
-		opts = append(opts, gitea.SetToken(token))
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &giteaForge{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: hc,
+	}
+}
+
+// sdk returns an SDK client whose requests carry ctx. The SDK keeps one
+// context per client, so each call gets its own rather than changing the
+// context of a shared one under another goroutine.
+func (f *giteaForge) sdk(ctx context.Context) *gitea.Client {
+	opts := []gitea.ClientOption{gitea.SetContext(ctx), gitea.SetHTTPClient(f.httpClient)}
+	if f.token != "" {
+		opts = append(opts, gitea.SetToken(f.token))
+	}
+	c, err := gitea.NewClient(f.baseURL, append(opts, gitea.SetGiteaVersion(f.serverVersion(ctx, opts)))...)
+	if err != nil {
+		// The version didn't parse; treat it as unknown.
+		c, _ = gitea.NewClient(f.baseURL, append(opts, gitea.SetGiteaVersion(""))...)
+	}
+	return c
+}
+
+// serverVersion returns the server's version, which the SDK uses to work
+// around older servers, reading it on first use. "" means unknown, and the
+// SDK then assumes a current server. Concurrent first calls share one read,
+// made without holding f.mu. A read cut short by ctx is retried on the next
+// call.
+func (f *giteaForge) serverVersion(ctx context.Context, opts []gitea.ClientOption) string {
+	f.mu.Lock()
+	version, known := f.version, f.versionKnown
+	f.mu.Unlock()
+	if known {
+		return version
+	}
+	version, err := f.versionRead.do(ctx, "", func(ctx context.Context) (string, error) {
+		c, _ := gitea.NewClient(f.baseURL, append(opts, gitea.SetContext(ctx), gitea.SetGiteaVersion(""))...)
+		version, _, err := c.ServerVersion()
+		if err != nil && ctx.Err() != nil {
+			return "", err
+		}
+		f.mu.Lock()
+		f.version, f.versionKnown = version, true
+		f.mu.Unlock()
+		return version, nil
+	})
+	if err != nil {
+		return ""
+	}
+	return version
+}
+
+func (f *giteaForge) getJSON(ctx context.Context, path string, v any) error {
+	u := f.baseURL + "/api/v1" + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	if f.token != "" {
+		req.Header.Set("Authorization", "token "+f.token)
+	}
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
 	}
-	if hc != nil {
-		opts = append(opts, gitea.SetHTTPClient(hc))
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &HTTPError{StatusCode: resp.StatusCode, URL: u, Body: string(body)}
 	}
-	c, _ := gitea.NewClient(baseURL, opts...)
-	return &giteaForge{client: c}
+
+	return json.NewDecoder(resp.Body).Decode(v)
 }
 
 func convertGiteaRepo(r *gitea.Repository) Repository {
 	result := Repository{
-		FullName:            r.FullName,
-		Owner:               r.Owner.UserName,
-		Name:                r.Name,
-		Description:         r.Description,
-		Homepage:            r.Website,
-		HTMLURL:             r.HTMLURL,
-		Language:            r.Language,
-		DefaultBranch:       r.DefaultBranch,
-		Fork:                r.Fork,
-		Archived:            r.Archived,
-		Private:             r.Private,
-		Size:                int(r.Size),
-		StargazersCount:     r.Stars,
-		ForksCount:          r.Forks,
-		OpenIssuesCount:     r.OpenIssues,
-		HasIssues:           r.HasIssues,
-		PullRequestsEnabled: r.HasPullRequests,
-		LogoURL:             r.AvatarURL,
-		CreatedAt:           r.Created,
-		UpdatedAt:           r.Updated,
+		FullName:              r.FullName,
+		Owner:                 r.Owner.UserName,
+		Name:                  r.Name,
+		Description:           r.Description,
+		Homepage:              r.Website,
+		HTMLURL:               r.HTMLURL,
+		Language:              r.Language,
+		DefaultBranch:         r.DefaultBranch,
+		Fork:                  r.Fork,
+		Archived:              r.Archived,
+		Private:               r.Private,
+		Size:                  int(r.Size),
+		StargazersCount:       r.Stars,
+		ForksCount:            r.Forks,
+		OpenIssuesCount:       r.OpenIssues,
+		OpenPullRequestsCount: r.OpenPulls,
+		SubscribersCount:      r.Watchers,
+		HasIssues:             r.HasIssues,
+		PullRequestsEnabled:   r.HasPullRequests,
+		Topics:                r.Topics,
+		LogoURL:               r.AvatarURL,
+		CreatedAt:             r.Created,
+		UpdatedAt:             r.Updated,
+		FieldsPopulated: FieldDescription | FieldHomepage | FieldLanguage |
+			FieldDefaultBranch | FieldArchived | FieldMirrorURL | FieldSize |
+			FieldStargazersCount | FieldForksCount | FieldOpenIssuesCount |
+			FieldOpenPullRequestsCount | FieldSubscribersCount,
 	}
 
 	if r.Mirror {
 		result.MirrorURL = r.OriginalURL
 	}
 
+	// Gitea 1.22 and later detect licenses and may list them on the
+	// repository.
+	if len(r.Licenses) > 0 {
+		setGiteaLicenses(&result, r.Licenses)
+	}
+
 	if r.Parent != nil {
 		result.SourceName = r.Parent.FullName
 	}
@@ -58,8 +158,25 @@ func convertGiteaRepo(r *gitea.Repository) Repository {
 	return result
 }
 
+// setGiteaLicenses records the licenses Gitea detected. When a repository
+// has several, the first is used and LicenseRaw keeps them all.
+func setGiteaLicenses(result *Repository, licenses []string) {
+	result.LicenseRaw = strings.Join(licenses, ", ")
+	if len(licenses) > 0 {
+		result.License = spdxLicense(licenses[0])
+	}
+	result.FieldsPopulated |= FieldLicense
+}
+
 func (f *giteaForge) FetchRepository(ctx context.Context, owner, repo string) (*Repository, error) {
-	r, resp, err := f.client.GetRepo(owner, repo)
+	return f.FetchRepositoryWithOptions(ctx, owner, repo, FetchOptions{})
+}
+
+// FetchRepositoryWithOptions only acts on DetectLicense: Gitea's repository
+// endpoint plus the topics and licenses calls already cover every field.
+func (f *giteaForge) FetchRepositoryWithOptions(ctx context.Context, owner, repo string, opts FetchOptions) (*Repository, error) {
+	client := f.sdk(ctx)
+	r, resp, err := client.GetRepo(owner, repo)
 	if err != nil {
 		if resp != nil && resp.StatusCode == http.StatusNotFound {
 			return nil, ErrNotFound
@@ -70,14 +187,59 @@ func (f *giteaForge) FetchRepository(ctx context.Context, owner, repo string) (*
 	result := convertGiteaRepo(r)
 
 	// Fetch topics separately (not included in main repo response)
-	topics, _, topicErr := f.client.ListRepoTopics(owner, repo, gitea.ListRepoTopicsOptions{})
+	topics, _, topicErr := client.ListRepoTopics(owner, repo, gitea.ListRepoTopicsOptions{})
 	if topicErr == nil {
 		result.Topics = topics
+		result.FieldsPopulated |= FieldTopics
+	}
+
+	if !result.FieldsPopulated.Has(FieldLicense) {
+		var licenses []string
+		path := "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo) + "/licenses"
+		err := f.getJSON(ctx, path, &licenses)
+		switch {
+		case err == nil:
+			setGiteaLicenses(&result, licenses)
+		case errors.Is(err, ErrNotFound):
+			// Servers older than 1.22 don't have the endpoint, so the
+			// license stays unknown.
+		default:
+			return nil, err
+		}
+	}
+
+	if opts.DetectLicense && result.License == "" && result.DefaultBranch != "" {
+		if err := f.detectLicense(ctx, owner, repo, &result); err != nil {
+			return nil, err
+		}
 	}
 
 	return &result, nil
 }
 
+func (f *giteaForge) detectLicense(ctx context.Context, owner, repo string, result *Repository) error {
+	client := f.sdk(ctx)
+	ref := result.DefaultBranch
+	entries, resp, err := client.ListContents(owner, repo, ref, "")
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.Type == "file" {
+			names = append(names, e.Name)
+		}
+	}
+	return detectRepositoryLicense(result, names, func(name string) (string, error) {
+		data, _, err := client.GetFile(owner, repo, ref, name)
+		return string(data), err
+	})
+}
+
 func (f *giteaForge) ListRepositories(ctx context.Context, owner string, opts ListOptions) ([]Repository, error) {
 	perPage := opts.PerPage
 	if perPage <= 0 {
@@ -96,11 +258,12 @@ func (f *giteaForge) ListRepositories(ctx context.Context, owner string, opts Li
 	return FilterRepos(repos, opts), nil
 }
 
-func (f *giteaForge) listOrgRepos(_ context.Context, owner string, perPage int) ([]Repository, error) {
+func (f *giteaForge) listOrgRepos(ctx context.Context, owner string, perPage int) ([]Repository, error) {
+	client := f.sdk(ctx)
 	var all []Repository
 	page := 1
 	for {
-		gRepos, resp, err := f.client.ListOrgRepos(owner, gitea.ListOrgReposOptions{
+		gRepos, resp, err := client.ListOrgRepos(owner, gitea.ListOrgReposOptions{
 			ListOptions: gitea.ListOptions{Page: page, PageSize: perPage},
 		})
 		if err != nil {
@@ -120,11 +283,12 @@ func (f *giteaForge) listOrgRepos(_ context.Context, owner string, perPage int)
 	return all, nil
 }
 
-func (f *giteaForge) listUserRepos(_ context.Context, owner string, perPage int) ([]Repository, error) {
+func (f *giteaForge) listUserRepos(ctx context.Context, owner string, perPage int) ([]Repository, error) {
+	client := f.sdk(ctx)
 	var all []Repository
 	page := 1
 	for {
-		gRepos, resp, err := f.client.ListUserRepos(owner, gitea.ListReposOptions{
+		gRepos, resp, err := client.ListUserRepos(owner, gitea.ListReposOptions{
 			ListOptions: gitea.ListOptions{Page: page, PageSize: perPage},
 		})
 		if err != nil {
@@ -145,10 +309,11 @@ func (f *giteaForge) listUserRepos(_ context.Context, owner string, perPage int)
 }
 
 func (f *giteaForge) FetchTags(ctx context.Context, owner, repo string) ([]Tag, error) {
+	client := f.sdk(ctx)
 	var allTags []Tag
 	page := 1
 	for {
-		tags, resp, err := f.client.ListRepoTags(owner, repo, gitea.ListRepoTagsOptions{
+		tags, resp, err := client.ListRepoTags(owner, repo, gitea.ListRepoTagsOptions{
 			ListOptions: gitea.ListOptions{Page: page, PageSize: 50},
 		})
 		if err != nil {
@@ -171,3 +336,268 @@ func (f *giteaForge) FetchTags(ctx context.Context, owner, repo string) ([]Tag,
 	}
 	return allTags, nil
 }
+
+func convertGiteaIssue(i *gitea.Issue) Issue {
+	result := Issue{
+		Number:    int(i.Index),
+		Title:     i.Title,
+		State:     string(i.State),
+		HTMLURL:   i.HTMLURL,
+		Comments:  i.Comments,
+		CreatedAt: i.Created,
+		UpdatedAt: i.Updated,
+	}
+	if i.Poster != nil {
+		result.Author = i.Poster.UserName
+	}
+	for _, l := range i.Labels {
+		result.Labels = append(result.Labels, l.Name)
+	}
+	if i.Closed != nil {
+		result.ClosedAt = *i.Closed
+	}
+	return result
+}
+
+func convertGiteaPullRequest(pr *gitea.PullRequest) PullRequest {
+	result := PullRequest{
+		Number:  int(pr.Index),
+		Title:   pr.Title,
+		State:   string(pr.State),
+		HTMLURL: pr.HTMLURL,
+		Draft:   pr.Draft,
+	}
+	if pr.Poster != nil {
+		result.Author = pr.Poster.UserName
+	}
+	if pr.Head != nil {
+		result.HeadBranch = pr.Head.Ref
+	}
+	if pr.Base != nil {
+		result.BaseBranch = pr.Base.Ref
+	}
+	for _, l := range pr.Labels {
+		result.Labels = append(result.Labels, l.Name)
+	}
+	if pr.Created != nil {
+		result.CreatedAt = *pr.Created
+	}
+	if pr.Updated != nil {
+		result.UpdatedAt = *pr.Updated
+	}
+	if pr.Closed != nil {
+		result.ClosedAt = *pr.Closed
+	}
+	if pr.HasMerged {
+		result.State = "merged"
+		if pr.Merged != nil {
+			result.MergedAt = *pr.Merged
+		}
+	}
+	return result
+}
+
+func (f *giteaForge) ListIssues(ctx context.Context, owner, repo string, opts IssueListOptions) ([]Issue, error) {
+	client := f.sdk(ctx)
+	perPage := opts.PerPage
+	if perPage <= 0 {
+		perPage = 50
+	}
+
+	var all []Issue
+	page := 1
+	for {
+		issues, resp, err := client.ListRepoIssues(owner, repo, gitea.ListIssueOption{
+			ListOptions: gitea.ListOptions{Page: page, PageSize: perPage},
+			State:       gitea.StateType(opts.State.String()),
+			Type:        gitea.IssueTypeIssue,
+		})
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+		for _, i := range issues {
+			all = append(all, convertGiteaIssue(i))
+		}
+		if len(issues) < perPage || reachedLimit(len(all), opts.Limit) {
+			break
+		}
+		page++
+	}
+	return truncate(all, opts.Limit), nil
+}
+
+func (f *giteaForge) ListPullRequests(ctx context.Context, owner, repo string, opts IssueListOptions) ([]PullRequest, error) {
+	client := f.sdk(ctx)
+	perPage := opts.PerPage
+	if perPage <= 0 {
+		perPage = 50
+	}
+
+	var all []PullRequest
+	page := 1
+	for {
+		prs, resp, err := client.ListRepoPullRequests(owner, repo, gitea.ListPullRequestsOptions{
+			ListOptions: gitea.ListOptions{Page: page, PageSize: perPage},
+			State:       gitea.StateType(opts.State.String()),
+		})
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+		for _, pr := range prs {
+			all = append(all, convertGiteaPullRequest(pr))
+		}
+		if len(prs) < perPage || reachedLimit(len(all), opts.Limit) {
+			break
+		}
+		page++
+	}
+	return truncate(all, opts.Limit), nil
+}
+
+func (f *giteaForge) FetchIssueCounts(ctx context.Context, owner, repo string) (*IssueCounts, error) {
+	client := f.sdk(ctx)
+	// Gitea already reports issues and pull requests separately.
+	r, resp, err := client.GetRepo(owner, repo)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &IssueCounts{
+		OpenIssues:       r.OpenIssues,
+		OpenPullRequests: r.OpenPulls,
+	}, nil
+}
+
+func (f *giteaForge) Search(ctx context.Context, query SearchQuery) ([]Repository, error) {
+	client := f.sdk(ctx)
+	perPage := query.PerPage
+	if perPage <= 0 {
+		perPage = 50
+	}
+
+	gOpts := gitea.SearchRepoOptions{
+		ListOptions:          gitea.ListOptions{Page: query.Page, PageSize: perPage},
+		Keyword:              query.Text,
+		KeywordInDescription: true,
+	}
+	// Gitea searches a single keyword either as text or as a topic. When
+	// both are given the topic is checked against the results instead.
+	if query.Text == "" && query.Topic != "" {
+		gOpts.Keyword = query.Topic
+		gOpts.KeywordIsTopic = true
+	}
+	switch query.Archived {
+	case ArchivedExclude:
+		gOpts.IsArchived = gitea.OptionalBool(false)
+	case ArchivedOnly:
+		gOpts.IsArchived = gitea.OptionalBool(true)
+	}
+	if query.Forks == ForkOnly {
+		gOpts.Type = gitea.RepoTypeFork
+	}
+	switch query.Sort {
+	case SortStars:
+		gOpts.Sort = "stars"
+	case SortForks:
+		gOpts.Sort = "forks"
+	case SortUpdated:
+		gOpts.Sort = "updated"
+	}
+	if gOpts.Sort != "" {
+		gOpts.Order = "desc"
+	}
+
+	gRepos, _, err := client.SearchRepos(gOpts)
+	if err != nil {
+		return nil, err
+	}
+	repos := make([]Repository, 0, len(gRepos))
+	for _, r := range gRepos {
+		repos = append(repos, convertGiteaRepo(r))
+	}
+	return FilterSearchResults(repos, query), nil
+}
+
+func (f *giteaForge) ListForks(ctx context.Context, owner, repo string) ([]Repository, error) {
+	client := f.sdk(ctx)
+	var all []Repository
+	page := 1
+	for {
+		forks, resp, err := client.ListForks(owner, repo, gitea.ListForksOptions{
+			ListOptions: gitea.ListOptions{Page: page, PageSize: 50},
+		})
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+		for _, r := range forks {
+			all = append(all, convertGiteaRepo(r))
+		}
+		if len(forks) < 50 {
+			break
+		}
+		page++
+	}
+	return markForks(all, owner+"/"+repo), nil
+}
+
+func (f *giteaForge) FetchActivity(ctx context.Context, owner, repo string, since time.Time) (*Activity, error) {
+	client := f.sdk(ctx)
+	b := newActivityBuilder(since, time.Now())
+
+	// Gitea can't filter commits by date, so page through the default
+	// branch (newest first) until the cutoff is passed.
+	page := 1
+	for {
+		commits, resp, err := client.ListRepoCommits(owner, repo, gitea.ListCommitOptions{
+			ListOptions: gitea.ListOptions{Page: page, PageSize: 50},
+		})
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, ErrNotFound
+			}
+			// Gitea answers 409 Conflict for repositories with no commits.
+			if resp != nil && resp.StatusCode == http.StatusConflict {
+				return b.result(), nil
+			}
+			return nil, err
+		}
+		done := len(commits) < 50
+		for _, c := range commits {
+			if c.CommitMeta == nil {
+				continue
+			}
+			if c.Created.Before(since) {
+				b.noteLastCommit(c.Created)
+				done = true
+				break
+			}
+			b.addCommit(giteaCommitAuthor(c), c.Created)
+		}
+		if done {
+			break
+		}
+		page++
+	}
+	return b.result(), nil
+}
+
+func giteaCommitAuthor(c *gitea.Commit) string {
+	if c.Author != nil && c.Author.UserName != "" {
+		return c.Author.UserName
+	}
+	if c.RepoCommit != nil && c.RepoCommit.Author != nil {
+		return c.RepoCommit.Author.Email
+	}
+	return ""
+}
@@ -3,6 +3,7 @@ package forges
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -11,7 +12,7 @@ import (
 )
 
 // giteaVersionHandler serves the /api/v1/version endpoint that the Gitea SDK
-// requires during client creation.
+// reads before its first request.
 func giteaVersionHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, `{"version":"1.21.0"}`)
 }
@@ -111,6 +112,43 @@ func TestGiteaFetchRepositoryNotFound(t *testing.T) {
 	}
 }
 
+func TestGiteaFetchRepositoryLicenses(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/version", giteaVersionHandler)
+	mux.HandleFunc("GET /api/v1/repos/testorg/testrepo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"full_name":      "testorg/testrepo",
+			"name":           "testrepo",
+			"watchers_count": 9,
+			"owner":          map[string]any{"login": "testorg"},
+		})
+	})
+	mux.HandleFunc("GET /api/v1/repos/testorg/testrepo/topics", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"topics": []string{}})
+	})
+	mux.HandleFunc("GET /api/v1/repos/testorg/testrepo/licenses", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "token test-token" {
+			t.Errorf("Authorization: got %q", got)
+		}
+		json.NewEncoder(w).Encode([]string{"mit", "Apache-2.0"})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGiteaForge(srv.URL, "test-token", nil)
+
+	repo, err := f.FetchRepository(context.Background(), "testorg", "testrepo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "License", "MIT", repo.License)
+	assertEqual(t, "LicenseRaw", "mit, Apache-2.0", repo.LicenseRaw)
+	assertEqualBool(t, "License populated", true, repo.FieldsPopulated.Has(FieldLicense|FieldTopics))
+	assertEqualInt(t, "SubscribersCount", 9, repo.SubscribersCount)
+	assertEqualBool(t, "SubscribersCount populated", true, repo.FieldsPopulated.Has(FieldSubscribersCount))
+}
+
 func TestGiteaListRepositories(t *testing.T) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /api/v1/version", giteaVersionHandler)
@@ -234,3 +272,285 @@ func TestGiteaFetchTags(t *testing.T) {
 	assertEqual(t, "Tag[1].Name", "v2.0.0", tags[1].Name)
 	assertEqual(t, "Tag[1].Commit", "ddd444", tags[1].Commit)
 }
+
+func TestGiteaListPullRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/version", giteaVersionHandler)
+	mux.HandleFunc("GET /api/v1/repos/testorg/testrepo/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("state"); got != "closed" {
+			t.Errorf("expected state=closed, got %q", got)
+		}
+		json.NewEncoder(w).Encode([]map[string]any{
+			{
+				"number":    4,
+				"title":     "Merged change",
+				"state":     "closed",
+				"merged":    true,
+				"merged_at": "2024-04-01T00:00:00Z",
+				"user":      map[string]any{"login": "dave"},
+				"head":      map[string]any{"ref": "topic"},
+				"base":      map[string]any{"ref": "main"},
+			},
+			{
+				"number": 3,
+				"title":  "Rejected change",
+				"state":  "closed",
+			},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGiteaForge(srv.URL, "", nil)
+
+	prs, err := f.ListPullRequests(context.Background(), "testorg", "testrepo", IssueListOptions{State: StateClosed})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prs) != 2 {
+		t.Fatalf("expected 2 pull requests, got %d", len(prs))
+	}
+	assertEqual(t, "prs[0].State", "merged", prs[0].State)
+	assertEqual(t, "prs[0].Author", "dave", prs[0].Author)
+	assertEqual(t, "prs[0].HeadBranch", "topic", prs[0].HeadBranch)
+	assertEqual(t, "prs[1].State", "closed", prs[1].State)
+}
+
+func TestGiteaListIssuesLimit(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/version", giteaVersionHandler)
+	mux.HandleFunc("GET /api/v1/repos/testorg/testrepo/issues", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("type"); got != "issues" {
+			t.Errorf("expected type=issues, got %q", got)
+		}
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"number": 3, "title": "Third", "state": "open", "labels": []map[string]any{{"name": "bug"}}},
+			{"number": 2, "title": "Second", "state": "open"},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGiteaForge(srv.URL, "", nil)
+
+	issues, err := f.ListIssues(context.Background(), "testorg", "testrepo", IssueListOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	assertEqualInt(t, "Number", 3, issues[0].Number)
+	assertSliceEqual(t, "Labels", []string{"bug"}, issues[0].Labels)
+}
+
+func TestGiteaFetchIssueCounts(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/version", giteaVersionHandler)
+	mux.HandleFunc("GET /api/v1/repos/testorg/testrepo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"full_name":         "testorg/testrepo",
+			"open_issues_count": 6,
+			"open_pr_counter":   2,
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGiteaForge(srv.URL, "", nil)
+
+	counts, err := f.FetchIssueCounts(context.Background(), "testorg", "testrepo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqualInt(t, "OpenIssues", 6, counts.OpenIssues)
+	assertEqualInt(t, "OpenPullRequests", 2, counts.OpenPullRequests)
+}
+
+func TestGiteaSearchByTopic(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/version", giteaVersionHandler)
+	mux.HandleFunc("GET /api/v1/repos/search", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assertEqual(t, "q", "selfhosted", q.Get("q"))
+		assertEqual(t, "topic", "true", q.Get("topic"))
+		json.NewEncoder(w).Encode(map[string]any{
+			"ok": true,
+			"data": []map[string]any{
+				{"full_name": "a/go-tool", "language": "Go", "topics": []string{"selfhosted"}, "owner": map[string]any{"login": "a"}},
+				{"full_name": "a/py-tool", "language": "Python", "topics": []string{"selfhosted"}, "owner": map[string]any{"login": "a"}},
+			},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGiteaForge(srv.URL, "", nil)
+
+	repos, err := f.Search(context.Background(), SearchQuery{Topic: "selfhosted", Language: "go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repos) != 1 {
+		t.Fatalf("expected 1 repo, got %d", len(repos))
+	}
+	assertEqual(t, "FullName", "a/go-tool", repos[0].FullName)
+	assertSliceEqual(t, "Topics", []string{"selfhosted"}, repos[0].Topics)
+}
+
+func TestGiteaListForks(t *testing.T) {
+	var pages []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/version", giteaVersionHandler)
+	mux.HandleFunc("GET /api/v1/repos/testorg/testrepo/forks", func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+		var forks []map[string]any
+		switch page {
+		case "1":
+			for i := range 50 {
+				owner := fmt.Sprintf("user%d", i)
+				forks = append(forks, map[string]any{
+					"full_name": owner + "/testrepo",
+					"name":      "testrepo",
+					"fork":      true,
+					"owner":     map[string]any{"login": owner},
+				})
+			}
+		case "2":
+			forks = append(forks, map[string]any{
+				"full_name":   "last/testrepo",
+				"name":        "testrepo",
+				"stars_count": 3,
+				"owner":       map[string]any{"login": "last"},
+				"parent":      map[string]any{"full_name": "testorg/testrepo"},
+			})
+		}
+		json.NewEncoder(w).Encode(forks)
+	})
+	mux.HandleFunc("GET /api/v1/repos/testorg/missing/forks", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGiteaForge(srv.URL, "", nil)
+	forks, err := f.ListForks(context.Background(), "testorg", "testrepo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSliceEqual(t, "pages", []string{"1", "2"}, pages)
+	if len(forks) != 51 {
+		t.Fatalf("expected 51 forks, got %d", len(forks))
+	}
+	assertEqual(t, "forks[0].FullName", "user0/testrepo", forks[0].FullName)
+	assertEqual(t, "forks[0].SourceName", "testorg/testrepo", forks[0].SourceName)
+	last := forks[50]
+	assertEqual(t, "last.FullName", "last/testrepo", last.FullName)
+	assertEqual(t, "last.Owner", "last", last.Owner)
+	assertEqualInt(t, "last.StargazersCount", 3, last.StargazersCount)
+	assertEqualBool(t, "last.Fork", true, last.Fork)
+	assertEqual(t, "last.SourceName", "testorg/testrepo", last.SourceName)
+
+	if _, err := f.ListForks(context.Background(), "testorg", "missing"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestGiteaFetchActivityStopsAtCutoff(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	since := now.Add(-48 * time.Hour)
+
+	var pages int
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/version", giteaVersionHandler)
+	mux.HandleFunc("GET /api/v1/repos/testorg/testrepo/commits", func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		commits := make([]map[string]any, 0, 50)
+		for i := range 50 {
+			// One commit per hour, so the cutoff falls inside the first page.
+			created := now.Add(-time.Duration(i) * time.Hour)
+			commits = append(commits, map[string]any{
+				"sha":     fmt.Sprintf("sha%d", i),
+				"created": created.Format(time.RFC3339),
+				"author":  map[string]any{"login": fmt.Sprintf("user%d", i%3)},
+			})
+		}
+		json.NewEncoder(w).Encode(commits)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGiteaForge(srv.URL, "", nil)
+
+	a, err := f.FetchActivity(context.Background(), "testorg", "testrepo", since)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pages != 1 {
+		t.Errorf("expected paging to stop after 1 page, got %d", pages)
+	}
+	assertEqualInt(t, "TotalCommits", 49, a.TotalCommits)
+	assertEqualInt(t, "Committers", 3, a.Committers)
+	assertEqual(t, "LastCommitAt", now.Format(time.RFC3339), a.LastCommitAt.Format(time.RFC3339))
+}
+
+func TestGiteaListRepositoriesCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pages := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/version", giteaVersionHandler)
+	mux.HandleFunc("GET /api/v1/orgs/bigorg/repos", func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		if pages == 3 {
+			cancel()
+		}
+		// Always a full page, so paging would never end on its own.
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"full_name": "bigorg/a", "owner": map[string]any{"login": "bigorg"}},
+			{"full_name": "bigorg/b", "owner": map[string]any{"login": "bigorg"}},
+		})
+	})
+	mux.HandleFunc("GET /api/v1/users/bigorg/repos", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a cancelled listing should not fall back to the user endpoint")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGiteaForge(srv.URL, "", nil)
+	_, err := f.ListRepositories(ctx, "bigorg", ListOptions{PerPage: 2})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	assertEqualInt(t, "pages", 3, pages)
+}
+
+func TestGiteaRequestTimeout(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/version", giteaVersionHandler)
+	mux.HandleFunc("GET /api/v1/repos/slow/repo", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGiteaForge(srv.URL, "", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := f.FetchRepository(ctx, "slow", "repo")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request took %s after its deadline", elapsed)
+	}
+}
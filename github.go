@@ -2,7 +2,11 @@ package forges
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v82/github"
 )
@@ -19,12 +23,28 @@ func newGitHubForge(token string, hc *http.Client) *gitHubForge {
 	return &gitHubForge{client: c}
 }
 
+// newGitHubForgeWithBase returns a backend for a GitHub Enterprise Server
+// instance rooted at baseURL. Without a token, requests are anonymous.
 func newGitHubForgeWithBase(baseURL, token string, hc *http.Client) *gitHubForge {
-	c := github.NewClient(hc).WithAuthToken(token)
-	c, _ = c.WithEnterpriseURLs(baseURL, baseURL)
+	c := github.NewClient(hc)
+	if token != "" {
+		c = c.WithAuthToken(token)
+	}
+	apiURL, uploadURL := gitHubEnterpriseURLs(baseURL)
+	c, _ = c.WithEnterpriseURLs(apiURL, uploadURL)
 	return &gitHubForge{client: c}
 }
 
+// gitHubEnterpriseURLs returns the REST API and upload endpoints of a GitHub
+// Enterprise Server instance from its root URL, which may already end in
+// /api/v3. go-github's own guessing skips hosts starting with "api.", which
+// is wrong for GHES, so both are spelled out.
+func gitHubEnterpriseURLs(baseURL string) (apiURL, uploadURL string) {
+	root := strings.TrimSuffix(baseURL, "/")
+	root = strings.TrimSuffix(root, "/api/v3")
+	return root + "/api/v3/", root + "/api/uploads/"
+}
+
 func convertGitHubRepo(r *github.Repository) Repository {
 	result := Repository{
 		FullName:            r.GetFullName(),
@@ -48,13 +68,23 @@ func convertGitHubRepo(r *github.Repository) Repository {
 		PullRequestsEnabled: true, // GitHub always has PRs enabled
 		Topics:              r.Topics,
 		LogoURL:             r.GetOwner().GetAvatarURL(),
+		// GitHub sends every field except subscribers_count in both single
+		// repository and list responses, using null for empty values.
+		FieldsPopulated: FieldDescription | FieldHomepage | FieldLanguage | FieldLicense |
+			FieldDefaultBranch | FieldArchived | FieldMirrorURL | FieldSize |
+			FieldStargazersCount | FieldForksCount | FieldOpenIssuesCount |
+			FieldTopics | FieldPushedAt,
+	}
+
+	if r.SubscribersCount != nil {
+		result.FieldsPopulated |= FieldSubscribersCount
 	}
 
 	if lic := r.GetLicense(); lic != nil {
-		spdx := lic.GetSPDXID()
-		if spdx != "" && spdx != "NOASSERTION" {
-			result.License = spdx
-		}
+		// GitHub reports NOASSERTION when it found a license it couldn't
+		// identify, which spdxLicense maps to "".
+		result.LicenseRaw = lic.GetSPDXID()
+		result.License = spdxLicense(result.LicenseRaw)
 	}
 
 	if parent := r.GetParent(); parent != nil {
@@ -75,6 +105,10 @@ func convertGitHubRepo(r *github.Repository) Repository {
 }
 
 func (f *gitHubForge) FetchRepository(ctx context.Context, owner, repo string) (*Repository, error) {
+	return f.FetchRepositoryWithOptions(ctx, owner, repo, FetchOptions{})
+}
+
+func (f *gitHubForge) FetchRepositoryWithOptions(ctx context.Context, owner, repo string, opts FetchOptions) (*Repository, error) {
 	r, resp, err := f.client.Repositories.Get(ctx, owner, repo)
 	if err != nil {
 		if resp != nil && resp.StatusCode == http.StatusNotFound {
@@ -83,10 +117,51 @@ func (f *gitHubForge) FetchRepository(ctx context.Context, owner, repo string) (
 		return nil, err
 	}
 
+	// The repository endpoint already returns everything Extended would add.
 	result := convertGitHubRepo(r)
+	if opts.IssueCounts {
+		openPRs, err := f.countOpenPullRequests(ctx, owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		result.OpenIssuesCount = max(result.OpenIssuesCount-openPRs, 0)
+		result.OpenPullRequestsCount = openPRs
+		result.FieldsPopulated |= FieldOpenPullRequestsCount
+	}
+	if opts.DetectLicense && result.License == "" {
+		if err := f.detectLicense(ctx, owner, repo, &result); err != nil {
+			return nil, err
+		}
+	}
 	return &result, nil
 }
 
+func (f *gitHubForge) detectLicense(ctx context.Context, owner, repo string, result *Repository) error {
+	ref := &github.RepositoryContentGetOptions{Ref: result.DefaultBranch}
+	_, dir, resp, err := f.client.Repositories.GetContents(ctx, owner, repo, "", ref)
+	if err != nil {
+		// Empty repositories have no contents at all.
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+
+	var names []string
+	for _, c := range dir {
+		if c.GetType() == "file" {
+			names = append(names, c.GetName())
+		}
+	}
+	return detectRepositoryLicense(result, names, func(name string) (string, error) {
+		file, _, _, err := f.client.Repositories.GetContents(ctx, owner, repo, name, ref)
+		if err != nil {
+			return "", err
+		}
+		return file.GetContent()
+	})
+}
+
 func (f *gitHubForge) ListRepositories(ctx context.Context, owner string, opts ListOptions) ([]Repository, error) {
 	perPage := opts.PerPage
 	if perPage <= 0 {
@@ -178,3 +253,388 @@ func (f *gitHubForge) FetchTags(ctx context.Context, owner, repo string) ([]Tag,
 	}
 	return allTags, nil
 }
+
+func convertGitHubIssue(i *github.Issue) Issue {
+	result := Issue{
+		Number:   i.GetNumber(),
+		Title:    i.GetTitle(),
+		State:    i.GetState(),
+		Author:   i.GetUser().GetLogin(),
+		HTMLURL:  i.GetHTMLURL(),
+		Comments: i.GetComments(),
+	}
+	for _, l := range i.Labels {
+		result.Labels = append(result.Labels, l.GetName())
+	}
+	if t := i.GetCreatedAt(); !t.IsZero() {
+		result.CreatedAt = t.Time
+	}
+	if t := i.GetUpdatedAt(); !t.IsZero() {
+		result.UpdatedAt = t.Time
+	}
+	if t := i.GetClosedAt(); !t.IsZero() {
+		result.ClosedAt = t.Time
+	}
+	return result
+}
+
+func convertGitHubPullRequest(pr *github.PullRequest) PullRequest {
+	result := PullRequest{
+		Number:     pr.GetNumber(),
+		Title:      pr.GetTitle(),
+		State:      pr.GetState(),
+		Author:     pr.GetUser().GetLogin(),
+		HTMLURL:    pr.GetHTMLURL(),
+		Draft:      pr.GetDraft(),
+		HeadBranch: pr.GetHead().GetRef(),
+		BaseBranch: pr.GetBase().GetRef(),
+	}
+	for _, l := range pr.Labels {
+		result.Labels = append(result.Labels, l.GetName())
+	}
+	if t := pr.GetCreatedAt(); !t.IsZero() {
+		result.CreatedAt = t.Time
+	}
+	if t := pr.GetUpdatedAt(); !t.IsZero() {
+		result.UpdatedAt = t.Time
+	}
+	if t := pr.GetClosedAt(); !t.IsZero() {
+		result.ClosedAt = t.Time
+	}
+	if t := pr.GetMergedAt(); !t.IsZero() {
+		result.MergedAt = t.Time
+		result.State = "merged"
+	}
+	return result
+}
+
+func (f *gitHubForge) ListIssues(ctx context.Context, owner, repo string, opts IssueListOptions) ([]Issue, error) {
+	perPage := opts.PerPage
+	if perPage <= 0 {
+		perPage = 100
+	}
+
+	var all []Issue
+	ghOpts := &github.IssueListByRepoOptions{
+		State:       opts.State.String(),
+		ListOptions: github.ListOptions{PerPage: perPage},
+	}
+	for {
+		issues, resp, err := f.client.Issues.ListByRepo(ctx, owner, repo, ghOpts)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+		for _, i := range issues {
+			// The issues endpoint also returns pull requests.
+			if i.IsPullRequest() {
+				continue
+			}
+			all = append(all, convertGitHubIssue(i))
+		}
+		if resp.NextPage == 0 || reachedLimit(len(all), opts.Limit) {
+			break
+		}
+		ghOpts.ListOptions.Page = resp.NextPage
+	}
+	return truncate(all, opts.Limit), nil
+}
+
+func (f *gitHubForge) ListPullRequests(ctx context.Context, owner, repo string, opts IssueListOptions) ([]PullRequest, error) {
+	perPage := opts.PerPage
+	if perPage <= 0 {
+		perPage = 100
+	}
+
+	var all []PullRequest
+	ghOpts := &github.PullRequestListOptions{
+		State:       opts.State.String(),
+		ListOptions: github.ListOptions{PerPage: perPage},
+	}
+	for {
+		prs, resp, err := f.client.PullRequests.List(ctx, owner, repo, ghOpts)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+		for _, pr := range prs {
+			all = append(all, convertGitHubPullRequest(pr))
+		}
+		if resp.NextPage == 0 || reachedLimit(len(all), opts.Limit) {
+			break
+		}
+		ghOpts.Page = resp.NextPage
+	}
+	return truncate(all, opts.Limit), nil
+}
+
+func (f *gitHubForge) countOpenPullRequests(ctx context.Context, owner, repo string) (int, error) {
+	// With one result per page, the last page number is the total count.
+	prs, resp, err := f.client.PullRequests.List(ctx, owner, repo, &github.PullRequestListOptions{
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if resp.LastPage > 0 {
+		return resp.LastPage, nil
+	}
+	return len(prs), nil
+}
+
+func (f *gitHubForge) FetchIssueCounts(ctx context.Context, owner, repo string) (*IssueCounts, error) {
+	r, resp, err := f.client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	openPRs, err := f.countOpenPullRequests(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub's open_issues_count includes open pull requests.
+	return &IssueCounts{
+		OpenIssues:       max(r.GetOpenIssuesCount()-openPRs, 0),
+		OpenPullRequests: openPRs,
+	}, nil
+}
+
+// gitHubSearchQuery translates a SearchQuery into GitHub search qualifiers.
+func gitHubSearchQuery(q SearchQuery) string {
+	var parts []string
+	if q.Text != "" {
+		parts = append(parts, q.Text)
+	}
+	if q.Topic != "" {
+		parts = append(parts, "topic:"+q.Topic)
+	}
+	if q.Language != "" {
+		parts = append(parts, "language:"+q.Language)
+	}
+	if q.MinStars > 0 {
+		parts = append(parts, fmt.Sprintf("stars:>=%d", q.MinStars))
+	}
+	switch q.Archived {
+	case ArchivedExclude:
+		parts = append(parts, "archived:false")
+	case ArchivedOnly:
+		parts = append(parts, "archived:true")
+	}
+	// GitHub search excludes forks unless asked for them.
+	switch q.Forks {
+	case ForkInclude:
+		parts = append(parts, "fork:true")
+	case ForkOnly:
+		parts = append(parts, "fork:only")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (f *gitHubForge) Search(ctx context.Context, query SearchQuery) ([]Repository, error) {
+	perPage := query.PerPage
+	if perPage <= 0 {
+		perPage = 100
+	}
+
+	opts := &github.SearchOptions{
+		ListOptions: github.ListOptions{Page: query.Page, PerPage: perPage},
+	}
+	switch query.Sort {
+	case SortStars:
+		opts.Sort = "stars"
+	case SortForks:
+		opts.Sort = "forks"
+	case SortUpdated:
+		opts.Sort = "updated"
+	}
+	if opts.Sort != "" {
+		opts.Order = "desc"
+	}
+
+	result, _, err := f.client.Search.Repositories(ctx, gitHubSearchQuery(query), opts)
+	if err != nil {
+		return nil, err
+	}
+	repos := make([]Repository, 0, len(result.Repositories))
+	for _, r := range result.Repositories {
+		repos = append(repos, convertGitHubRepo(r))
+	}
+	return repos, nil
+}
+
+func (f *gitHubForge) ListForks(ctx context.Context, owner, repo string) ([]Repository, error) {
+	var all []Repository
+	ghOpts := &github.RepositoryListForksOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		forks, resp, err := f.client.Repositories.ListForks(ctx, owner, repo, ghOpts)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+		for _, r := range forks {
+			all = append(all, convertGitHubRepo(r))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		ghOpts.Page = resp.NextPage
+	}
+	return markForks(all, owner+"/"+repo), nil
+}
+
+// gitHubStatsWindow is how far back GitHub's statistics endpoints reach.
+const gitHubStatsWindow = 52 * week
+
+func gitHubCommitAuthor(c *github.RepositoryCommit) string {
+	if login := c.GetAuthor().GetLogin(); login != "" {
+		return login
+	}
+	return c.GetCommit().GetAuthor().GetEmail()
+}
+
+func (f *gitHubForge) FetchActivity(ctx context.Context, owner, repo string, since time.Time) (*Activity, error) {
+	now := time.Now()
+	b := newActivityBuilder(since, now)
+
+	latest, resp, err := f.client.Repositories.ListCommits(ctx, owner, repo, &github.CommitsListOptions{
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		// GitHub answers 409 Conflict for repositories with no commits.
+		if resp != nil && resp.StatusCode == http.StatusConflict {
+			return b.result(), nil
+		}
+		return nil, err
+	}
+	for _, c := range latest {
+		b.noteLastCommit(c.GetCommit().GetCommitter().GetDate().Time)
+	}
+
+	// The statistics endpoints are cheap but only cover the last year and
+	// answer 202 Accepted while GitHub computes them in the background.
+	if !since.Before(now.Add(-gitHubStatsWindow)) {
+		ok, err := f.activityFromStats(ctx, owner, repo, since, b)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return b.result(), nil
+		}
+	}
+
+	if err := f.listActivity(ctx, owner, repo, since, time.Time{}, b); err != nil {
+		return nil, err
+	}
+	return b.result(), nil
+}
+
+// listActivity adds the commits between since and until to b, with a zero
+// until meaning up to now.
+func (f *gitHubForge) listActivity(ctx context.Context, owner, repo string, since, until time.Time, b *activityBuilder) error {
+	ghOpts := &github.CommitsListOptions{
+		Since:       since,
+		Until:       until,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		commits, resp, err := f.client.Repositories.ListCommits(ctx, owner, repo, ghOpts)
+		if err != nil {
+			return err
+		}
+		for _, c := range commits {
+			b.addCommit(gitHubCommitAuthor(c), c.GetCommit().GetCommitter().GetDate().Time)
+		}
+		if resp.NextPage == 0 {
+			return nil
+		}
+		ghOpts.Page = resp.NextPage
+	}
+}
+
+// activityFromStats fills b from the weekly statistics endpoints. It reports
+// false if GitHub hasn't finished computing them yet, or if the server
+// doesn't serve them. The repository is known to exist by then, so a 404
+// means the endpoint is missing rather than the repository. The statistics only
+// come in whole weeks, so when since falls mid-week the commits of that first
+// week are listed instead.
+//
+// The contributor statistics leave out commits that aren't linked to an
+// account, and authors beyond the top 100. Those authors can only be told
+// apart by email in the commit listing, so it reports false as well when
+// the contributors' commits don't add up to the weekly totals.
+func (f *gitHubForge) activityFromStats(ctx context.Context, owner, repo string, since time.Time, b *activityBuilder) (bool, error) {
+	var accepted *github.AcceptedError
+	first := weekStart(since)
+	if first.Before(since) {
+		first = first.Add(week)
+	}
+
+	weeks, resp, err := f.client.Repositories.ListCommitActivity(ctx, owner, repo)
+	if errors.As(err, &accepted) || (resp != nil && resp.StatusCode == http.StatusNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	contributors, resp, err := f.client.Repositories.ListContributorsStats(ctx, owner, repo)
+	if errors.As(err, &accepted) || (resp != nil && resp.StatusCode == http.StatusNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	total, linked := 0, 0
+	for _, w := range weeks {
+		if !w.GetWeek().Time.Before(first) {
+			total += w.GetTotal()
+		}
+	}
+	var committers []string
+	for _, c := range contributors {
+		commits := 0
+		for _, w := range c.Weeks {
+			if !w.GetWeek().Time.Before(first) {
+				commits += w.GetCommits()
+			}
+		}
+		if commits > 0 {
+			linked += commits
+			committers = append(committers, c.GetAuthor().GetLogin())
+		}
+	}
+	if linked != total {
+		return false, nil
+	}
+
+	for _, w := range weeks {
+		if t := w.GetWeek().Time; !t.Before(first) {
+			b.addWeek(t, w.GetTotal())
+		}
+	}
+	for _, login := range committers {
+		b.addCommitter(login)
+	}
+	if since.Before(first) {
+		if err := f.listActivity(ctx, owner, repo, since, first, b); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
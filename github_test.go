@@ -2,10 +2,13 @@ package forges
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/google/go-github/v82/github"
 )
@@ -129,6 +132,78 @@ func TestGitHubFetchRepositoryNoassertionLicense(t *testing.T) {
 	if repo.License != "" {
 		t.Errorf("expected empty license for NOASSERTION, got %q", repo.License)
 	}
+	assertEqual(t, "LicenseRaw", "NOASSERTION", repo.LicenseRaw)
+}
+
+func TestGitHubFetchRepositoryUntabledLicense(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/test/sleepy", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(github.Repository{
+			FullName: ptr("test/sleepy"),
+			Name:     ptr("sleepy"),
+			Owner:    &github.User{Login: ptr("test")},
+			License:  &github.License{SPDXID: ptr("Sleepycat")},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := github.NewClient(nil)
+	c, _ = c.WithEnterpriseURLs(srv.URL+"/api/v3", srv.URL+"/api/v3")
+	f := &gitHubForge{client: c}
+
+	repo, err := f.FetchRepository(context.Background(), "test", "sleepy")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "License", "Sleepycat", repo.License)
+}
+
+func TestGitHubFetchRepositoryDetectLicense(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/test/noassertion", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(github.Repository{
+			FullName:      ptr("test/noassertion"),
+			Name:          ptr("noassertion"),
+			Owner:         &github.User{Login: ptr("test")},
+			DefaultBranch: ptr("main"),
+			License:       &github.License{SPDXID: ptr("NOASSERTION")},
+		})
+	})
+	mux.HandleFunc("GET /api/v3/repos/test/noassertion/contents/", func(w http.ResponseWriter, r *http.Request) {
+		assertEqual(t, "ref", "main", r.URL.Query().Get("ref"))
+		json.NewEncoder(w).Encode([]*github.RepositoryContent{
+			{Name: ptr("README.md"), Type: ptr("file")},
+			{Name: ptr("LICENSE"), Type: ptr("file")},
+			{Name: ptr("licenses"), Type: ptr("dir")},
+		})
+	})
+	mux.HandleFunc("GET /api/v3/repos/test/noassertion/contents/LICENSE", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&github.RepositoryContent{
+			Name:     ptr("LICENSE"),
+			Type:     ptr("file"),
+			Encoding: ptr("base64"),
+			Content:  ptr(base64.StdEncoding.EncodeToString([]byte(mitLicenseText))),
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := github.NewClient(nil)
+	c, _ = c.WithEnterpriseURLs(srv.URL+"/api/v3", srv.URL+"/api/v3")
+	f := &gitHubForge{client: c}
+
+	repo, err := f.FetchRepositoryWithOptions(context.Background(), "test", "noassertion", FetchOptions{DetectLicense: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "License", "MIT", repo.License)
+	assertEqual(t, "LicenseRaw", "NOASSERTION", repo.LicenseRaw)
+	if repo.LicenseConfidence < minLicenseConfidence {
+		t.Errorf("LicenseConfidence: got %v", repo.LicenseConfidence)
+	}
 }
 
 func TestGitHubListRepositories(t *testing.T) {
@@ -289,3 +364,434 @@ func TestGitHubFetchTags(t *testing.T) {
 	assertEqual(t, "Tag[1].Name", "v0.9.0", tags[1].Name)
 	assertEqual(t, "Tag[1].Commit", "def456", tags[1].Commit)
 }
+
+func TestGitHubListIssuesSkipsPullRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/issues", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("state"); got != "closed" {
+			t.Errorf("expected state=closed, got %q", got)
+		}
+		json.NewEncoder(w).Encode([]map[string]any{
+			{
+				"number":    1,
+				"title":     "Bug report",
+				"state":     "closed",
+				"html_url":  "https://github.com/octocat/hello-world/issues/1",
+				"comments":  3,
+				"user":      map[string]any{"login": "alice"},
+				"labels":    []map[string]any{{"name": "bug"}},
+				"closed_at": "2024-02-01T00:00:00Z",
+			},
+			{
+				"number":       2,
+				"title":        "Fix bug",
+				"state":        "closed",
+				"pull_request": map[string]any{"url": "https://api.github.com/repos/octocat/hello-world/pulls/2"},
+			},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := github.NewClient(nil)
+	c, _ = c.WithEnterpriseURLs(srv.URL+"/api/v3", srv.URL+"/api/v3")
+	f := &gitHubForge{client: c}
+
+	issues, err := f.ListIssues(context.Background(), "octocat", "hello-world", IssueListOptions{State: StateClosed})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	assertEqualInt(t, "Number", 1, issues[0].Number)
+	assertEqual(t, "State", "closed", issues[0].State)
+	assertEqual(t, "Author", "alice", issues[0].Author)
+	assertEqualInt(t, "Comments", 3, issues[0].Comments)
+	assertSliceEqual(t, "Labels", []string{"bug"}, issues[0].Labels)
+}
+
+func TestGitHubListPullRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/pulls", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{
+				"number":    5,
+				"title":     "Add feature",
+				"state":     "closed",
+				"draft":     false,
+				"user":      map[string]any{"login": "bob"},
+				"head":      map[string]any{"ref": "feature"},
+				"base":      map[string]any{"ref": "main"},
+				"merged_at": "2024-03-01T00:00:00Z",
+			},
+			{
+				"number": 6,
+				"title":  "WIP",
+				"state":  "open",
+				"draft":  true,
+			},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := github.NewClient(nil)
+	c, _ = c.WithEnterpriseURLs(srv.URL+"/api/v3", srv.URL+"/api/v3")
+	f := &gitHubForge{client: c}
+
+	prs, err := f.ListPullRequests(context.Background(), "octocat", "hello-world", IssueListOptions{State: StateAll})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prs) != 2 {
+		t.Fatalf("expected 2 pull requests, got %d", len(prs))
+	}
+	assertEqual(t, "prs[0].State", "merged", prs[0].State)
+	assertEqual(t, "prs[0].HeadBranch", "feature", prs[0].HeadBranch)
+	assertEqual(t, "prs[0].BaseBranch", "main", prs[0].BaseBranch)
+	assertEqual(t, "prs[1].State", "open", prs[1].State)
+	assertEqualBool(t, "prs[1].Draft", true, prs[1].Draft)
+}
+
+func TestGitHubFetchIssueCounts(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(github.Repository{
+			FullName:        ptr("octocat/hello-world"),
+			OpenIssuesCount: ptrInt(10),
+		})
+	})
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/pulls", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `<`+r.URL.Path+`?page=3&per_page=1>; rel="last"`)
+		json.NewEncoder(w).Encode([]map[string]any{{"number": 9}})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := github.NewClient(nil)
+	c, _ = c.WithEnterpriseURLs(srv.URL+"/api/v3", srv.URL+"/api/v3")
+	f := &gitHubForge{client: c}
+
+	counts, err := f.FetchIssueCounts(context.Background(), "octocat", "hello-world")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqualInt(t, "OpenIssues", 7, counts.OpenIssues)
+	assertEqualInt(t, "OpenPullRequests", 3, counts.OpenPullRequests)
+}
+
+func TestGitHubSearchQuery(t *testing.T) {
+	tests := []struct {
+		query SearchQuery
+		want  string
+	}{
+		{SearchQuery{Text: "parser"}, "parser fork:true"},
+		{SearchQuery{Topic: "cli", Language: "go", MinStars: 50, Forks: ForkExclude}, "topic:cli language:go stars:>=50"},
+		{SearchQuery{Text: "x", Archived: ArchivedExclude, Forks: ForkOnly}, "x archived:false fork:only"},
+	}
+	for _, tt := range tests {
+		assertEqual(t, "query", tt.want, gitHubSearchQuery(tt.query))
+	}
+}
+
+func TestGitHubSearch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/search/repositories", func(w http.ResponseWriter, r *http.Request) {
+		assertEqual(t, "q", "yaml language:go fork:true", r.URL.Query().Get("q"))
+		assertEqual(t, "sort", "stars", r.URL.Query().Get("sort"))
+		assertEqual(t, "page", "2", r.URL.Query().Get("page"))
+		json.NewEncoder(w).Encode(map[string]any{
+			"total_count": 1,
+			"items": []map[string]any{
+				{"full_name": "go-yaml/yaml", "name": "yaml", "stargazers_count": 7000},
+			},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := github.NewClient(nil)
+	c, _ = c.WithEnterpriseURLs(srv.URL+"/api/v3", srv.URL+"/api/v3")
+	f := &gitHubForge{client: c}
+
+	repos, err := f.Search(context.Background(), SearchQuery{Text: "yaml", Language: "go", Sort: SortStars, Page: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repos) != 1 {
+		t.Fatalf("expected 1 repo, got %d", len(repos))
+	}
+	assertEqual(t, "FullName", "go-yaml/yaml", repos[0].FullName)
+	assertEqualInt(t, "StargazersCount", 7000, repos[0].StargazersCount)
+}
+
+func TestGitHubListForks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/forks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]*github.Repository{
+			{FullName: ptr("alice/hello-world"), Name: ptr("hello-world"), Fork: ptrBool(true), Owner: &github.User{Login: ptr("alice")}},
+			{FullName: ptr("bob/hello-world"), Name: ptr("hello-world"), Fork: ptrBool(true), Owner: &github.User{Login: ptr("bob")}},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := github.NewClient(nil)
+	c, _ = c.WithEnterpriseURLs(srv.URL+"/api/v3", srv.URL+"/api/v3")
+	f := &gitHubForge{client: c}
+
+	forks, err := f.ListForks(context.Background(), "octocat", "hello-world")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(forks) != 2 {
+		t.Fatalf("expected 2 forks, got %d", len(forks))
+	}
+	assertEqual(t, "forks[0].FullName", "alice/hello-world", forks[0].FullName)
+	assertEqual(t, "forks[0].SourceName", "octocat/hello-world", forks[0].SourceName)
+	assertEqualBool(t, "forks[1].Fork", true, forks[1].Fork)
+}
+
+func TestGitHubFetchActivityFromStats(t *testing.T) {
+	since := weekStart(time.Now()).Add(-week)
+	last := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/commits", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"sha": "abc", "commit": map[string]any{"committer": map[string]any{"date": last.Format(time.RFC3339)}}},
+		})
+	})
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/stats/commit_activity", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"week": since.Add(-week).Unix(), "total": 9},
+			{"week": since.Unix(), "total": 4},
+			{"week": since.Add(week).Unix(), "total": 2},
+		})
+	})
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/stats/contributors", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"author": map[string]any{"login": "alice"}, "weeks": []map[string]any{{"w": since.Unix(), "c": 4}}},
+			{"author": map[string]any{"login": "bob"}, "weeks": []map[string]any{{"w": since.Add(week).Unix(), "c": 2}}},
+			{"author": map[string]any{"login": "old"}, "weeks": []map[string]any{{"w": since.Add(-week).Unix(), "c": 9}}},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := github.NewClient(nil)
+	c, _ = c.WithEnterpriseURLs(srv.URL+"/api/v3", srv.URL+"/api/v3")
+	f := &gitHubForge{client: c}
+
+	a, err := f.FetchActivity(context.Background(), "octocat", "hello-world", since)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqualInt(t, "TotalCommits", 6, a.TotalCommits)
+	assertEqualInt(t, "Committers", 2, a.Committers)
+	assertEqual(t, "LastCommitAt", last.Format(time.RFC3339), a.LastCommitAt.Format(time.RFC3339))
+	if len(a.Weeks) != 2 {
+		t.Fatalf("expected 2 weeks, got %d", len(a.Weeks))
+	}
+	assertEqualInt(t, "Weeks[0].Commits", 4, a.Weeks[0].Commits)
+}
+
+func TestGitHubFetchActivityFromStatsPartialWeek(t *testing.T) {
+	first := weekStart(time.Now()).Add(-week)
+	since := first.Add(3 * 24 * time.Hour) // mid-week
+	inWeek := since.Add(time.Hour).UTC().Format(time.RFC3339)
+
+	var listed url.Values
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/commits", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("since") == "" {
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"sha": "a1", "commit": map[string]any{"committer": map[string]any{"date": inWeek}}},
+			})
+			return
+		}
+		listed = r.URL.Query()
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"sha": "a1", "author": map[string]any{"login": "carol"}, "commit": map[string]any{"committer": map[string]any{"date": inWeek}}},
+		})
+	})
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/stats/commit_activity", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"week": first.Unix(), "total": 7}, // mostly before since
+			{"week": first.Add(week).Unix(), "total": 2},
+		})
+	})
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/stats/contributors", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"author": map[string]any{"login": "alice"}, "weeks": []map[string]any{{"w": first.Unix(), "c": 6}}},
+			{"author": map[string]any{"login": "bob"}, "weeks": []map[string]any{{"w": first.Add(week).Unix(), "c": 2}}},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := github.NewClient(nil)
+	c, _ = c.WithEnterpriseURLs(srv.URL+"/api/v3", srv.URL+"/api/v3")
+	f := &gitHubForge{client: c}
+
+	a, err := f.FetchActivity(context.Background(), "octocat", "hello-world", since)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(a.Weeks) != 2 {
+		t.Fatalf("expected 2 weeks, got %d", len(a.Weeks))
+	}
+	assertEqualInt(t, "Weeks[0].Commits", 1, a.Weeks[0].Commits)
+	assertEqualInt(t, "Weeks[1].Commits", 2, a.Weeks[1].Commits)
+	assertEqualInt(t, "TotalCommits", 3, a.TotalCommits)
+	assertEqualInt(t, "Committers", 2, a.Committers) // carol and bob, not alice
+	assertEqual(t, "listed until", first.Add(week).Format(time.RFC3339), listed.Get("until"))
+}
+
+func TestGitHubFetchActivityUnlinkedAuthor(t *testing.T) {
+	recent := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/commits", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"sha": "a1", "author": map[string]any{"login": "alice"}, "commit": map[string]any{"committer": map[string]any{"date": recent}}},
+			{"sha": "a2", "commit": map[string]any{"author": map[string]any{"email": "bob@example.com"}, "committer": map[string]any{"date": recent}}},
+		})
+	})
+	// bob's commit isn't linked to an account, so the contributor
+	// statistics leave it out.
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/stats/commit_activity", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"week": weekStart(time.Now()).Unix(), "total": 2},
+		})
+	})
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/stats/contributors", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"author": map[string]any{"login": "alice"}, "weeks": []map[string]any{{"w": weekStart(time.Now()).Unix(), "c": 1}}},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := github.NewClient(nil)
+	c, _ = c.WithEnterpriseURLs(srv.URL+"/api/v3", srv.URL+"/api/v3")
+	f := &gitHubForge{client: c}
+
+	// The first window is served by the statistics, the second only by
+	// the commit listing. Both count bob by email.
+	for _, since := range []time.Time{weekStart(time.Now()), time.Now().AddDate(-2, 0, 0)} {
+		a, err := f.FetchActivity(context.Background(), "octocat", "hello-world", since)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEqualInt(t, "TotalCommits", 2, a.TotalCommits)
+		assertEqualInt(t, "Committers", 2, a.Committers)
+	}
+}
+
+func TestGitHubFetchActivityStatsPending(t *testing.T) {
+	since := time.Now().Add(-3 * 24 * time.Hour)
+	recent := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/commits", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("since") == "" {
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"sha": "a1", "commit": map[string]any{"committer": map[string]any{"date": recent}}},
+			})
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"sha": "a1", "author": map[string]any{"login": "alice"}, "commit": map[string]any{"committer": map[string]any{"date": recent}}},
+			{"sha": "a2", "commit": map[string]any{"author": map[string]any{"email": "bob@example.com"}, "committer": map[string]any{"date": recent}}},
+		})
+	})
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/stats/commit_activity", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := github.NewClient(nil)
+	c, _ = c.WithEnterpriseURLs(srv.URL+"/api/v3", srv.URL+"/api/v3")
+	f := &gitHubForge{client: c}
+
+	a, err := f.FetchActivity(context.Background(), "octocat", "hello-world", since)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqualInt(t, "TotalCommits", 2, a.TotalCommits)
+	assertEqualInt(t, "Committers", 2, a.Committers)
+}
+
+func TestGitHubEnterpriseURLs(t *testing.T) {
+	tests := []struct {
+		baseURL, api, upload string
+	}{
+		{"https://ghe.example.com", "https://ghe.example.com/api/v3/", "https://ghe.example.com/api/uploads/"},
+		{"https://ghe.example.com/api/v3/", "https://ghe.example.com/api/v3/", "https://ghe.example.com/api/uploads/"},
+		{"https://api.example.com/github", "https://api.example.com/github/api/v3/", "https://api.example.com/github/api/uploads/"},
+	}
+	for _, tt := range tests {
+		api, upload := gitHubEnterpriseURLs(tt.baseURL)
+		assertEqual(t, tt.baseURL+" api", tt.api, api)
+		assertEqual(t, tt.baseURL+" upload", tt.upload, upload)
+	}
+}
+
+func TestGitHubEnterpriseAuth(t *testing.T) {
+	var auth string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world", func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewEncoder(w).Encode(github.Repository{FullName: ptr("octocat/hello-world")})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGitHubForgeWithBase(srv.URL, "", nil)
+	if _, err := f.FetchRepository(context.Background(), "octocat", "hello-world"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "anonymous Authorization", "", auth)
+
+	f = newGitHubForgeWithBase(srv.URL, "secret", nil)
+	if _, err := f.FetchRepository(context.Background(), "octocat", "hello-world"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "Authorization", "Bearer secret", auth)
+}
+
+func TestGitHubFetchActivityStatsMissing(t *testing.T) {
+	since := weekStart(time.Now()).Add(-week)
+	recent := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/commits", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"sha": "a1", "author": map[string]any{"login": "alice"}, "commit": map[string]any{"committer": map[string]any{"date": recent}}},
+		})
+	})
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world/stats/commit_activity", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGitHubForgeWithBase(srv.URL, "", nil)
+	a, err := f.FetchActivity(context.Background(), "octocat", "hello-world", since)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqualInt(t, "TotalCommits", 1, a.TotalCommits)
+}
@@ -0,0 +1,257 @@
+package forges
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitHubApp holds the credentials of a GitHub App installation. Requests
+// are made with installation access tokens minted from them.
+type GitHubApp struct {
+	AppID      int64
+	PrivateKey []byte // PEM, as downloaded from the app's settings page
+	// InstallationID selects the installation. When it is 0, the
+	// installation on Owner (an organization or user) is looked up.
+	InstallationID int64
+	Owner          string
+}
+
+// gitHubAppTokenMargin is how long before expiry an installation token is
+// replaced, so requests never go out with one about to expire.
+const gitHubAppTokenMargin = 5 * time.Minute
+
+// gitHubAppTransport authenticates requests as a GitHub App installation.
+// Installation tokens live for an hour; one is cached and refreshed shortly
+// before it expires.
+type gitHubAppTransport struct {
+	base    http.RoundTripper
+	apiURL  string // REST API root, with trailing slash
+	apiHost string // only requests to this host get the token
+	app     GitHubApp
+	key     *rsa.PrivateKey
+
+	mint           flightGroup[string]
+	mu             sync.Mutex
+	installationID int64
+	token          string
+	expires        time.Time
+}
+
+func newGitHubAppTransport(base http.RoundTripper, apiURL string, app GitHubApp) (*gitHubAppTransport, error) {
+	if app.AppID == 0 {
+		return nil, errors.New("github app: AppID is required")
+	}
+	if app.InstallationID == 0 && app.Owner == "" {
+		return nil, errors.New("github app: InstallationID or Owner is required")
+	}
+	key, err := parseRSAPrivateKey(app.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("github app: %w", err)
+	}
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("github app: invalid API URL %q: %w", apiURL, err)
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &gitHubAppTransport{
+		base:           base,
+		apiURL:         apiURL,
+		apiHost:        strings.ToLower(u.Host),
+		app:            app,
+		key:            key,
+		installationID: app.InstallationID,
+	}, nil
+}
+
+// parseRSAPrivateKey reads a PEM private key in PKCS #1 form, as GitHub
+// issues them, or PKCS #8.
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// RoundTrip adds the installation token to requests for the API host.
+// Requests elsewhere, such as redirects to pre-signed asset URLs, go out
+// without it. A 401 drops the cached token so the next request mints a
+// fresh one.
+func (t *gitHubAppTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.ToLower(req.URL.Host) != t.apiHost {
+		return t.base.RoundTrip(req)
+	}
+	token, err := t.installationToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "token "+token)
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		t.mu.Lock()
+		if t.token == token {
+			t.token = ""
+		}
+		t.mu.Unlock()
+	}
+	return resp, err
+}
+
+// installationToken returns the cached installation token, minting a new
+// one when it is missing or close to expiry. Concurrent requests share one
+// mint, and requests don't wait on the lock while it is in flight.
+func (t *gitHubAppTransport) installationToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	token, expires := t.token, t.expires
+	t.mu.Unlock()
+	if token != "" && time.Until(expires) > gitHubAppTokenMargin {
+		return token, nil
+	}
+	return t.mint.do(ctx, "", t.mintToken)
+}
+
+// mintToken fetches a new installation token and caches it.
+func (t *gitHubAppTransport) mintToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	id := t.installationID
+	t.mu.Unlock()
+	if id == 0 {
+		var err error
+		if id, err = t.findInstallation(ctx); err != nil {
+			return "", err
+		}
+		t.mu.Lock()
+		t.installationID = id
+		t.mu.Unlock()
+	}
+
+	var resp struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	path := "app/installations/" + strconv.FormatInt(id, 10) + "/access_tokens"
+	if err := t.appRequest(ctx, http.MethodPost, path, &resp); err != nil {
+		return "", fmt.Errorf("github app: minting installation token: %w", err)
+	}
+	t.mu.Lock()
+	t.token, t.expires = resp.Token, resp.ExpiresAt
+	t.mu.Unlock()
+	return resp.Token, nil
+}
+
+// findInstallation looks up the app's installation on the configured owner,
+// trying it as an organization first and then as a user.
+func (t *gitHubAppTransport) findInstallation(ctx context.Context) (int64, error) {
+	var inst struct {
+		ID int64 `json:"id"`
+	}
+	err := t.appRequest(ctx, http.MethodGet, "orgs/"+t.app.Owner+"/installation", &inst)
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+		err = t.appRequest(ctx, http.MethodGet, "users/"+t.app.Owner+"/installation", &inst)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("github app: finding installation for %s: %w", t.app.Owner, err)
+	}
+	return inst.ID, nil
+}
+
+// appRequest calls the API authenticated as the app itself.
+func (t *gitHubAppTransport) appRequest(ctx context.Context, method, path string, v any) error {
+	jwt, err := t.appJWT(time.Now())
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, t.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &HTTPError{StatusCode: resp.StatusCode, URL: req.URL.String(), Body: string(body)}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// appJWT returns the short-lived RS256 JSON Web Token that authenticates as
+// the app. iat is backdated a minute to allow for clock drift, and GitHub
+// rejects tokens valid for more than ten minutes.
+func (t *gitHubAppTransport) appJWT(now time.Time) (string, error) {
+	header := `{"alg":"RS256","typ":"JWT"}`
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(t.app.AppID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString([]byte(header)) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, t.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + enc.EncodeToString(sig), nil
+}
+
+// gitHubAPIURL returns the REST API root for a GitHub instance's base URL.
+func gitHubAPIURL(baseURL string) string {
+	if strings.TrimSuffix(baseURL, "/") == "https://github.com" {
+		return "https://api.github.com/"
+	}
+	apiURL, _ := gitHubEnterpriseURLs(baseURL)
+	return apiURL
+}
+
+// gitHubAppClient wraps hc to authenticate as the app installation.
+func gitHubAppClient(hc *http.Client, apiURL string, app GitHubApp) (*http.Client, error) {
+	transport, err := newGitHubAppTransport(nil, apiURL, app)
+	if err != nil {
+		return nil, err
+	}
+	return wrapTransport(hc, func(base http.RoundTripper) http.RoundTripper {
+		transport.base = base
+		return transport
+	}), nil
+}
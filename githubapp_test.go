@@ -0,0 +1,231 @@
+package forges
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testRSAKey = sync.OnceValue(func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+})
+
+func testRSAKeyPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(testRSAKey()),
+	})
+}
+
+// verifyAppJWT checks a Bearer JWT against the test key and returns its
+// issuer.
+func verifyAppJWT(t *testing.T, r *http.Request) string {
+	t.Helper()
+	jwt, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		t.Fatalf("expected Bearer JWT, got %q", r.Header.Get("Authorization"))
+	}
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("malformed JWT %q", jwt)
+	}
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&testRSAKey().PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+		t.Fatalf("JWT signature: %v", err)
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		Iss string `json:"iss"`
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+	}
+	json.Unmarshal(payload, &claims)
+	if claims.Exp-claims.Iat > 600 {
+		t.Errorf("JWT valid for %ds, GitHub allows at most 600", claims.Exp-claims.Iat)
+	}
+	return claims.Iss
+}
+
+func TestGitHubAppInstallationToken(t *testing.T) {
+	mints := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v3/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		assertEqual(t, "iss", "7", verifyAppJWT(t, r))
+		mints++
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{
+			"token":      "ghs_installation",
+			"expires_at": time.Now().Add(time.Hour),
+		})
+	})
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world", func(w http.ResponseWriter, r *http.Request) {
+		assertEqual(t, "Authorization", "token ghs_installation", r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(map[string]any{"full_name": "octocat/hello-world"})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := NewClient(
+		WithGitHub(srv.URL, ""),
+		WithGitHubApp(srv.URL, GitHubApp{AppID: 7, PrivateKey: testRSAKeyPEM(), InstallationID: 42}),
+		WithInsecureHTTP("127.0.0.1"),
+	)
+	for range 2 {
+		if _, err := c.FetchRepository(context.Background(), srv.URL+"/octocat/hello-world"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	assertEqualInt(t, "mints", 1, mints)
+}
+
+func TestGitHubAppTokenRefresh(t *testing.T) {
+	mints := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		mints++
+		// Inside the refresh margin, so every request needs a new token.
+		json.NewEncoder(w).Encode(map[string]any{
+			"token":      "ghs_short",
+			"expires_at": time.Now().Add(time.Minute),
+		})
+	})
+	mux.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	hc, err := gitHubAppClient(nil, srv.URL+"/", GitHubApp{AppID: 7, PrivateKey: testRSAKeyPEM(), InstallationID: 42})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range 2 {
+		resp, err := hc.Get(srv.URL + "/ping")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+	assertEqualInt(t, "mints", 2, mints)
+}
+
+func TestGitHubAppTokenScope(t *testing.T) {
+	var assetAuth string
+	assets := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assetAuth = r.Header.Get("Authorization")
+	}))
+	defer assets.Close()
+
+	mints := 0
+	revoked := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		mints++
+		json.NewEncoder(w).Encode(map[string]any{
+			"token":      fmt.Sprintf("ghs_%d", mints),
+			"expires_at": time.Now().Add(time.Hour),
+		})
+	})
+	mux.HandleFunc("GET /release", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, assets.URL+"/signed-asset", http.StatusFound)
+	})
+	mux.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {
+		if revoked && r.Header.Get("Authorization") == "token ghs_1" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	hc, err := gitHubAppClient(nil, srv.URL+"/", GitHubApp{AppID: 7, PrivateKey: testRSAKeyPEM(), InstallationID: 42})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	get := func(path string) int {
+		t.Helper()
+		resp, err := hc.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	get("/release")
+	assertEqual(t, "asset host Authorization", "", assetAuth)
+
+	// A revoked token is dropped after the 401, and the next request mints
+	// a new one.
+	revoked = true
+	assertEqualInt(t, "revoked status", http.StatusUnauthorized, get("/ping"))
+	assertEqualInt(t, "status after re-mint", http.StatusOK, get("/ping"))
+	assertEqualInt(t, "mints", 2, mints)
+}
+
+func TestGitHubAppFindsInstallation(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orgs/octocat/installation", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("GET /users/octocat/installation", func(w http.ResponseWriter, r *http.Request) {
+		verifyAppJWT(t, r)
+		json.NewEncoder(w).Encode(map[string]any{"id": 99})
+	})
+	mux.HandleFunc("POST /app/installations/99/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"token":      "ghs_user",
+			"expires_at": time.Now().Add(time.Hour),
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tr, err := newGitHubAppTransport(nil, srv.URL+"/", GitHubApp{AppID: 7, PrivateKey: testRSAKeyPEM(), Owner: "octocat"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token, err := tr.installationToken(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "token", "ghs_user", token)
+}
+
+func TestGitHubAppInvalidConfig(t *testing.T) {
+	tests := []GitHubApp{
+		{PrivateKey: testRSAKeyPEM(), InstallationID: 1},
+		{AppID: 7, PrivateKey: testRSAKeyPEM()},
+		{AppID: 7, PrivateKey: []byte("not a key"), InstallationID: 1},
+	}
+	for _, app := range tests {
+		if _, err := newGitHubAppTransport(nil, "https://api.github.com/", app); err == nil {
+			t.Errorf("expected error for %+v", app)
+		}
+	}
+
+	c := NewClient(WithGitHubApp("github.com", GitHubApp{AppID: 7}))
+	if _, err := c.ForgeFor("github.com"); err == nil {
+		t.Error("expected registration error for invalid app")
+	}
+}
+
+func TestGitHubAPIURL(t *testing.T) {
+	assertEqual(t, "github.com", "https://api.github.com/", gitHubAPIURL("https://github.com"))
+	assertEqual(t, "GHES", "https://ghe.example.com/api/v3/", gitHubAPIURL("https://ghe.example.com"))
+}
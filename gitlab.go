@@ -3,6 +3,7 @@ package forges
 import (
 	"context"
 	"net/http"
+	"time"
 
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 )
@@ -11,10 +12,13 @@ type gitLabForge struct {
 	client *gitlab.Client
 }
 
-func newGitLabForge(baseURL, token string, hc *http.Client) *gitLabForge {
+// newGitLabForge builds a GitLab backend. extra options are passed on to the
+// GitLab client.
+func newGitLabForge(baseURL, token string, hc *http.Client, extra ...gitlab.ClientOptionFunc) *gitLabForge {
 	opts := []gitlab.ClientOptionFunc{
 		gitlab.WithBaseURL(baseURL + "/api/v4"),
 	}
+	opts = append(opts, extra...)
 	if hc != nil {
 		opts = append(opts, gitlab.WithHTTPClient(hc))
 	}
@@ -37,6 +41,23 @@ func convertGitLabProject(p *gitlab.Project) Repository {
 		HasIssues:           true,
 		PullRequestsEnabled: p.MergeRequestsEnabled,
 		Topics:              p.Topics,
+		FieldsPopulated: FieldDescription | FieldDefaultBranch | FieldArchived |
+			FieldStargazersCount | FieldForksCount | FieldOpenIssuesCount | FieldTopics,
+	}
+
+	// GitLab only shows the import URL of a pull mirror to maintainers.
+	if !p.Mirror {
+		result.FieldsPopulated |= FieldMirrorURL
+	} else if p.ImportURL != "" {
+		result.MirrorURL = p.ImportURL
+		result.FieldsPopulated |= FieldMirrorURL
+	}
+
+	// Statistics are only included on request and report bytes, while the
+	// other forges report kilobytes.
+	if p.Statistics != nil {
+		result.Size = int(p.Statistics.RepositorySize / 1024)
+		result.FieldsPopulated |= FieldSize
 	}
 
 	if p.Namespace != nil {
@@ -45,7 +66,9 @@ func convertGitLabProject(p *gitlab.Project) Repository {
 	}
 
 	if p.License != nil {
-		result.License = p.License.Key
+		result.LicenseRaw = p.License.Key
+		result.License = NormalizeLicense(p.License.Key)
+		result.FieldsPopulated |= FieldLicense
 	}
 
 	if p.ForkedFromProject != nil {
@@ -64,11 +87,19 @@ func convertGitLabProject(p *gitlab.Project) Repository {
 }
 
 func (f *gitLabForge) FetchRepository(ctx context.Context, owner, repo string) (*Repository, error) {
+	return f.FetchRepositoryWithOptions(ctx, owner, repo, FetchOptions{})
+}
+
+func (f *gitLabForge) FetchRepositoryWithOptions(ctx context.Context, owner, repo string, opts FetchOptions) (*Repository, error) {
 	pid := owner + "/" + repo
 	license := true
-	p, resp, err := f.client.Projects.GetProject(pid, &gitlab.GetProjectOptions{
+	glOpts := &gitlab.GetProjectOptions{
 		License: &license,
-	})
+	}
+	if opts.Extended {
+		glOpts.Statistics = gitlab.Ptr(true)
+	}
+	p, resp, err := f.client.Projects.GetProject(pid, glOpts, gitlab.WithContext(ctx))
 	if err != nil {
 		if resp != nil && resp.StatusCode == http.StatusNotFound {
 			return nil, ErrNotFound
@@ -77,9 +108,98 @@ func (f *gitLabForge) FetchRepository(ctx context.Context, owner, repo string) (
 	}
 
 	result := convertGitLabProject(p)
+	// The license was requested, so a missing one means there is none.
+	result.FieldsPopulated |= FieldLicense
+
+	if opts.Extended {
+		if err := f.enrichProject(ctx, pid, &result); err != nil {
+			return nil, err
+		}
+	}
+	if opts.IssueCounts {
+		openMRs, err := f.countOpenMergeRequests(ctx, pid)
+		if err != nil {
+			return nil, err
+		}
+		result.OpenPullRequestsCount = openMRs
+		result.FieldsPopulated |= FieldOpenPullRequestsCount
+	}
+	if opts.DetectLicense && result.License == "" {
+		if err := f.detectLicense(ctx, pid, &result); err != nil {
+			return nil, err
+		}
+	}
 	return &result, nil
 }
 
+func (f *gitLabForge) detectLicense(ctx context.Context, pid string, result *Repository) error {
+	var ref *string
+	if result.DefaultBranch != "" {
+		ref = gitlab.Ptr(result.DefaultBranch)
+	}
+
+	var names []string
+	opts := &gitlab.ListTreeOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+		Ref:         ref,
+	}
+	for {
+		nodes, resp, err := f.client.Repositories.ListTree(pid, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			// Empty repositories have no tree.
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil
+			}
+			return err
+		}
+		for _, n := range nodes {
+			if n.Type == "blob" {
+				names = append(names, n.Name)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return detectRepositoryLicense(result, names, func(name string) (string, error) {
+		data, _, err := f.client.RepositoryFiles.GetRawFile(pid, name, &gitlab.GetRawFileOptions{Ref: ref}, gitlab.WithContext(ctx))
+		return string(data), err
+	})
+}
+
+// enrichProject fills the language and last push time, which GitLab's
+// project endpoint doesn't include. Homepage and SubscribersCount have no
+// GitLab equivalent at all: projects have no website field, and watchers
+// are only visible as the caller's own notification level.
+func (f *gitLabForge) enrichProject(ctx context.Context, pid string, result *Repository) error {
+	langs, _, err := f.client.Projects.GetProjectLanguages(pid, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	var top float32
+	for lang, share := range *langs {
+		if share > top || (share == top && lang < result.Language) {
+			result.Language, top = lang, share
+		}
+	}
+	result.FieldsPopulated |= FieldLanguage
+
+	// GitLab has no pushed_at, so use the newest commit on the default branch.
+	commits, _, err := f.client.Commits.ListCommits(pid, &gitlab.ListCommitsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 1},
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	if len(commits) > 0 && commits[0].CommittedDate != nil {
+		result.PushedAt = *commits[0].CommittedDate
+	}
+	result.FieldsPopulated |= FieldPushedAt
+	return nil
+}
+
 func (f *gitLabForge) ListRepositories(ctx context.Context, owner string, opts ListOptions) ([]Repository, error) {
 	perPage := opts.PerPage
 	if perPage <= 0 {
@@ -104,7 +224,7 @@ func (f *gitLabForge) listGroupProjects(ctx context.Context, group string, perPa
 		ListOptions: gitlab.ListOptions{PerPage: int64(perPage)},
 	}
 	for {
-		projects, resp, err := f.client.Groups.ListGroupProjects(group, glOpts)
+		projects, resp, err := f.client.Groups.ListGroupProjects(group, glOpts, gitlab.WithContext(ctx))
 		if err != nil {
 			if resp != nil && resp.StatusCode == http.StatusNotFound {
 				return nil, ErrOwnerNotFound
@@ -128,7 +248,7 @@ func (f *gitLabForge) listUserProjects(ctx context.Context, user string, perPage
 		ListOptions: gitlab.ListOptions{PerPage: int64(perPage)},
 	}
 	for {
-		projects, resp, err := f.client.Projects.ListUserProjects(user, glOpts)
+		projects, resp, err := f.client.Projects.ListUserProjects(user, glOpts, gitlab.WithContext(ctx))
 		if err != nil {
 			if resp != nil && resp.StatusCode == http.StatusNotFound {
 				return nil, ErrOwnerNotFound
@@ -153,7 +273,7 @@ func (f *gitLabForge) FetchTags(ctx context.Context, owner, repo string) ([]Tag,
 		ListOptions: gitlab.ListOptions{PerPage: 100},
 	}
 	for {
-		tags, resp, err := f.client.Tags.ListTags(pid, opts)
+		tags, resp, err := f.client.Tags.ListTags(pid, opts, gitlab.WithContext(ctx))
 		if err != nil {
 			if resp != nil && resp.StatusCode == http.StatusNotFound {
 				return nil, ErrNotFound
@@ -174,3 +294,303 @@ func (f *gitLabForge) FetchTags(ctx context.Context, owner, repo string) ([]Tag,
 	}
 	return allTags, nil
 }
+
+// gitLabState converts a GitLab issue or merge request state to the
+// normalized names used by Issue and PullRequest.
+func gitLabState(state string) string {
+	switch state {
+	case "opened", "locked":
+		return "open"
+	default:
+		return state
+	}
+}
+
+func convertGitLabIssue(i *gitlab.Issue) Issue {
+	result := Issue{
+		Number:   int(i.IID),
+		Title:    i.Title,
+		State:    gitLabState(i.State),
+		HTMLURL:  i.WebURL,
+		Labels:   i.Labels,
+		Comments: int(i.UserNotesCount),
+	}
+	if i.Author != nil {
+		result.Author = i.Author.Username
+	}
+	if i.CreatedAt != nil {
+		result.CreatedAt = *i.CreatedAt
+	}
+	if i.UpdatedAt != nil {
+		result.UpdatedAt = *i.UpdatedAt
+	}
+	if i.ClosedAt != nil {
+		result.ClosedAt = *i.ClosedAt
+	}
+	return result
+}
+
+func convertGitLabMergeRequest(mr *gitlab.BasicMergeRequest) PullRequest {
+	result := PullRequest{
+		Number:     int(mr.IID),
+		Title:      mr.Title,
+		State:      gitLabState(mr.State),
+		HTMLURL:    mr.WebURL,
+		Draft:      mr.Draft,
+		HeadBranch: mr.SourceBranch,
+		BaseBranch: mr.TargetBranch,
+		Labels:     mr.Labels,
+	}
+	if mr.Author != nil {
+		result.Author = mr.Author.Username
+	}
+	if mr.CreatedAt != nil {
+		result.CreatedAt = *mr.CreatedAt
+	}
+	if mr.UpdatedAt != nil {
+		result.UpdatedAt = *mr.UpdatedAt
+	}
+	if mr.ClosedAt != nil {
+		result.ClosedAt = *mr.ClosedAt
+	}
+	if mr.MergedAt != nil {
+		result.MergedAt = *mr.MergedAt
+	}
+	return result
+}
+
+func (f *gitLabForge) ListIssues(ctx context.Context, owner, repo string, opts IssueListOptions) ([]Issue, error) {
+	perPage := opts.PerPage
+	if perPage <= 0 {
+		perPage = 100
+	}
+
+	pid := owner + "/" + repo
+	glOpts := &gitlab.ListProjectIssuesOptions{
+		ListOptions: gitlab.ListOptions{PerPage: int64(perPage)},
+	}
+	switch opts.State {
+	case StateOpen:
+		glOpts.State = gitlab.Ptr("opened")
+	case StateClosed:
+		glOpts.State = gitlab.Ptr("closed")
+	}
+
+	var all []Issue
+	for {
+		issues, resp, err := f.client.Issues.ListProjectIssues(pid, glOpts, gitlab.WithContext(ctx))
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+		for _, i := range issues {
+			all = append(all, convertGitLabIssue(i))
+		}
+		if resp.NextPage == 0 || reachedLimit(len(all), opts.Limit) {
+			break
+		}
+		glOpts.Page = resp.NextPage
+	}
+	return truncate(all, opts.Limit), nil
+}
+
+func (f *gitLabForge) ListPullRequests(ctx context.Context, owner, repo string, opts IssueListOptions) ([]PullRequest, error) {
+	perPage := opts.PerPage
+	if perPage <= 0 {
+		perPage = 100
+	}
+
+	// GitLab's "closed" state excludes merged requests, so closed is
+	// served from "all" and filtered below to match the other forges.
+	pid := owner + "/" + repo
+	glOpts := &gitlab.ListProjectMergeRequestsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: int64(perPage)},
+	}
+	if opts.State == StateOpen {
+		glOpts.State = gitlab.Ptr("opened")
+	}
+
+	var all []PullRequest
+	for {
+		mrs, resp, err := f.client.MergeRequests.ListProjectMergeRequests(pid, glOpts, gitlab.WithContext(ctx))
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+		for _, mr := range mrs {
+			pr := convertGitLabMergeRequest(mr)
+			if opts.State == StateClosed && pr.State == "open" {
+				continue
+			}
+			all = append(all, pr)
+		}
+		if resp.NextPage == 0 || reachedLimit(len(all), opts.Limit) {
+			break
+		}
+		glOpts.Page = resp.NextPage
+	}
+	return truncate(all, opts.Limit), nil
+}
+
+func (f *gitLabForge) countOpenMergeRequests(ctx context.Context, pid string) (int, error) {
+	// GitLab reports the total in the X-Total header. It omits the header
+	// for very large result sets, in which case only the page is counted.
+	mrs, resp, err := f.client.MergeRequests.ListProjectMergeRequests(pid, &gitlab.ListProjectMergeRequestsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 1},
+		State:       gitlab.Ptr("opened"),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	if resp.TotalItems > 0 {
+		return int(resp.TotalItems), nil
+	}
+	return len(mrs), nil
+}
+
+func (f *gitLabForge) FetchIssueCounts(ctx context.Context, owner, repo string) (*IssueCounts, error) {
+	pid := owner + "/" + repo
+	p, resp, err := f.client.Projects.GetProject(pid, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	openMRs, err := f.countOpenMergeRequests(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IssueCounts{
+		OpenIssues:       int(p.OpenIssuesCount),
+		OpenPullRequests: openMRs,
+	}, nil
+}
+
+func (f *gitLabForge) Search(ctx context.Context, query SearchQuery) ([]Repository, error) {
+	perPage := query.PerPage
+	if perPage <= 0 {
+		perPage = 100
+	}
+
+	glOpts := &gitlab.ListProjectsOptions{
+		ListOptions: gitlab.ListOptions{Page: int64(query.Page), PerPage: int64(perPage)},
+	}
+	if query.Text != "" {
+		glOpts.Search = gitlab.Ptr(query.Text)
+	}
+	if query.Topic != "" {
+		glOpts.Topic = gitlab.Ptr(query.Topic)
+	}
+	if query.Language != "" {
+		glOpts.WithProgrammingLanguage = gitlab.Ptr(query.Language)
+	}
+	switch query.Archived {
+	case ArchivedExclude:
+		glOpts.Archived = gitlab.Ptr(false)
+	case ArchivedOnly:
+		glOpts.Archived = gitlab.Ptr(true)
+	}
+	// GitLab can't order projects by fork count; it falls back to the
+	// default ordering.
+	switch query.Sort {
+	case SortStars:
+		glOpts.OrderBy = gitlab.Ptr("star_count")
+	case SortUpdated:
+		glOpts.OrderBy = gitlab.Ptr("last_activity_at")
+	}
+	if glOpts.OrderBy != nil {
+		glOpts.Sort = gitlab.Ptr("desc")
+	}
+
+	projects, _, err := f.client.Projects.ListProjects(glOpts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	repos := make([]Repository, 0, len(projects))
+	for _, p := range projects {
+		repos = append(repos, convertGitLabProject(p))
+	}
+
+	// Project listings don't include the language, so that filter is only
+	// applied server-side. Stars and forks are filtered here.
+	rest := query
+	rest.Language, rest.Topic, rest.Archived = "", "", ArchivedInclude
+	return FilterSearchResults(repos, rest), nil
+}
+
+func (f *gitLabForge) ListForks(ctx context.Context, owner, repo string) ([]Repository, error) {
+	pid := owner + "/" + repo
+	var all []Repository
+	glOpts := &gitlab.ListProjectsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+	}
+	for {
+		projects, resp, err := f.client.Projects.ListProjectForks(pid, glOpts, gitlab.WithContext(ctx))
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+		for _, p := range projects {
+			all = append(all, convertGitLabProject(p))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		glOpts.Page = resp.NextPage
+	}
+	return markForks(all, pid), nil
+}
+
+func (f *gitLabForge) FetchActivity(ctx context.Context, owner, repo string, since time.Time) (*Activity, error) {
+	pid := owner + "/" + repo
+	b := newActivityBuilder(since, time.Now())
+
+	// Without a ref GitLab lists the default branch.
+	glOpts := &gitlab.ListCommitsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+		Since:       &since,
+	}
+	for {
+		commits, resp, err := f.client.Commits.ListCommits(pid, glOpts, gitlab.WithContext(ctx))
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+		for _, c := range commits {
+			if c.CommittedDate != nil {
+				b.addCommit(c.AuthorEmail, *c.CommittedDate)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		glOpts.Page = resp.NextPage
+	}
+
+	// Nothing since the cutoff, so look up the latest commit separately.
+	if b.activity.LastCommitAt.IsZero() {
+		latest, _, err := f.client.Commits.ListCommits(pid, &gitlab.ListCommitsOptions{
+			ListOptions: gitlab.ListOptions{PerPage: 1},
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range latest {
+			if c.CommittedDate != nil {
+				b.noteLastCommit(*c.CommittedDate)
+			}
+		}
+	}
+	return b.result(), nil
+}
@@ -3,8 +3,10 @@ package forges
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -67,7 +69,8 @@ func TestGitLabFetchRepository(t *testing.T) {
 	assertEqualInt(t, "ForksCount", 7, repo.ForksCount)
 	assertEqualInt(t, "OpenIssuesCount", 3, repo.OpenIssuesCount)
 	assertEqualBool(t, "PullRequestsEnabled", true, repo.PullRequestsEnabled)
-	assertEqual(t, "License", "apache-2.0", repo.License)
+	assertEqual(t, "License", "Apache-2.0", repo.License)
+	assertEqual(t, "LicenseRaw", "apache-2.0", repo.LicenseRaw)
 	assertEqualBool(t, "Fork", true, repo.Fork)
 	assertEqual(t, "SourceName", "upstream/myrepo", repo.SourceName)
 	assertEqual(t, "LogoURL", "https://gitlab.com/uploads/-/system/group/avatar/123/logo.png", repo.LogoURL)
@@ -92,6 +95,60 @@ func TestGitLabFetchRepositoryNotFound(t *testing.T) {
 	}
 }
 
+func TestGitLabFetchRepositoryExtended(t *testing.T) {
+	pushed := time.Date(2024, 6, 14, 9, 0, 0, 0, time.UTC)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/mygroup%2Fmyrepo", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("statistics") != "true" {
+			t.Errorf("expected statistics=true, got %q", r.URL.RawQuery)
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":                  1,
+			"path_with_namespace": "mygroup/myrepo",
+			"name":                "myrepo",
+			"mirror":              true,
+			"import_url":          "https://github.com/upstream/myrepo.git",
+			"statistics":          map[string]any{"repository_size": 2 * 1024 * 1024},
+			"namespace":           map[string]any{"path": "mygroup"},
+		})
+	})
+	mux.HandleFunc("GET /api/v4/projects/mygroup%2Fmyrepo/languages", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]float32{"Go": 80.5, "Shell": 19.5})
+	})
+	mux.HandleFunc("GET /api/v4/projects/mygroup%2Fmyrepo/repository/commits", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"id": "abc123", "committed_date": pushed.Format(time.RFC3339)},
+		})
+	})
+	mux.HandleFunc("GET /api/v4/projects/mygroup%2Fmyrepo/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Total", "6")
+		json.NewEncoder(w).Encode([]map[string]any{{"id": 1, "iid": 1}})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGitLabForge(srv.URL, "", nil)
+
+	repo, err := f.FetchRepositoryWithOptions(context.Background(), "mygroup", "myrepo", FetchOptions{Extended: true, IssueCounts: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertEqual(t, "Language", "Go", repo.Language)
+	assertEqualInt(t, "Size", 2048, repo.Size)
+	assertEqual(t, "MirrorURL", "https://github.com/upstream/myrepo.git", repo.MirrorURL)
+	assertEqualInt(t, "OpenPullRequestsCount", 6, repo.OpenPullRequestsCount)
+	if !repo.PushedAt.Equal(pushed) {
+		t.Errorf("PushedAt: want %v, got %v", pushed, repo.PushedAt)
+	}
+	want := FieldLanguage | FieldSize | FieldMirrorURL | FieldPushedAt | FieldLicense | FieldOpenPullRequestsCount
+	assertEqualBool(t, "FieldsPopulated", true, repo.FieldsPopulated.Has(want))
+	assertEqualBool(t, "Homepage populated", false, repo.FieldsPopulated.Has(FieldHomepage))
+	assertEqualBool(t, "SubscribersCount populated", false, repo.FieldsPopulated.Has(FieldSubscribersCount))
+}
+
 func TestGitLabListRepositories(t *testing.T) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /api/v4/groups/mygroup/projects", func(w http.ResponseWriter, r *http.Request) {
@@ -203,3 +260,235 @@ func TestGitLabFetchTags(t *testing.T) {
 	assertEqual(t, "Tag[1].Name", "v1.0.0", tags[1].Name)
 	assertEqual(t, "Tag[1].Commit", "bbb222", tags[1].Commit)
 }
+
+func TestGitLabListPullRequestsClosedIncludesMerged(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/mygroup%2Fmyrepo/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("state"); got != "" {
+			t.Errorf("expected no state filter, got %q", got)
+		}
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"iid": 1, "title": "Open MR", "state": "opened"},
+			{"iid": 2, "title": "Merged MR", "state": "merged", "source_branch": "feat", "target_branch": "main",
+				"merged_at": "2024-01-02T00:00:00Z", "author": map[string]any{"username": "carol"}},
+			{"iid": 3, "title": "Closed MR", "state": "closed"},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGitLabForge(srv.URL, "", nil)
+
+	prs, err := f.ListPullRequests(context.Background(), "mygroup", "myrepo", IssueListOptions{State: StateClosed})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prs) != 2 {
+		t.Fatalf("expected 2 merge requests, got %d", len(prs))
+	}
+	assertEqual(t, "prs[0].State", "merged", prs[0].State)
+	assertEqual(t, "prs[0].Author", "carol", prs[0].Author)
+	assertEqual(t, "prs[0].HeadBranch", "feat", prs[0].HeadBranch)
+	assertEqual(t, "prs[1].State", "closed", prs[1].State)
+}
+
+func TestGitLabListIssues(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/mygroup%2Fmyrepo/issues", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("state"); got != "opened" {
+			t.Errorf("expected state=opened, got %q", got)
+		}
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"id": 1012, "iid": 12, "title": "Crash", "state": "opened", "labels": []string{"bug"}, "user_notes_count": 2},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGitLabForge(srv.URL, "", nil)
+
+	issues, err := f.ListIssues(context.Background(), "mygroup", "myrepo", IssueListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	assertEqualInt(t, "Number", 12, issues[0].Number)
+	assertEqual(t, "State", "open", issues[0].State)
+	assertEqualInt(t, "Comments", 2, issues[0].Comments)
+	assertSliceEqual(t, "Labels", []string{"bug"}, issues[0].Labels)
+}
+
+func TestGitLabFetchIssueCounts(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/mygroup%2Fmyrepo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"path_with_namespace": "mygroup/myrepo", "open_issues_count": 8})
+	})
+	mux.HandleFunc("GET /api/v4/projects/mygroup%2Fmyrepo/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Total", "5")
+		json.NewEncoder(w).Encode([]map[string]any{{"iid": 1, "state": "opened"}})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGitLabForge(srv.URL, "", nil)
+
+	counts, err := f.FetchIssueCounts(context.Background(), "mygroup", "myrepo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqualInt(t, "OpenIssues", 8, counts.OpenIssues)
+	assertEqualInt(t, "OpenPullRequests", 5, counts.OpenPullRequests)
+}
+
+func TestGitLabSearch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assertEqual(t, "search", "parser", q.Get("search"))
+		assertEqual(t, "topic", "cli", q.Get("topic"))
+		assertEqual(t, "with_programming_language", "Go", q.Get("with_programming_language"))
+		assertEqual(t, "archived", "false", q.Get("archived"))
+		assertEqual(t, "order_by", "star_count", q.Get("order_by"))
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"path_with_namespace": "a/popular", "star_count": 40, "topics": []string{"cli"}},
+			{"path_with_namespace": "a/obscure", "star_count": 2, "topics": []string{"cli"}},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGitLabForge(srv.URL, "", nil)
+
+	repos, err := f.Search(context.Background(), SearchQuery{
+		Text:     "parser",
+		Topic:    "cli",
+		Language: "Go",
+		MinStars: 10,
+		Archived: ArchivedExclude,
+		Sort:     SortStars,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repos) != 1 {
+		t.Fatalf("expected 1 repo, got %d", len(repos))
+	}
+	assertEqual(t, "FullName", "a/popular", repos[0].FullName)
+}
+
+func TestGitLabListForks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/mygroup%2Fmyrepo/forks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{
+				"path_with_namespace": "someone/myrepo",
+				"name":                "myrepo",
+				"forked_from_project": map[string]any{"path_with_namespace": "mygroup/myrepo"},
+			},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGitLabForge(srv.URL, "", nil)
+
+	forks, err := f.ListForks(context.Background(), "mygroup", "myrepo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(forks) != 1 {
+		t.Fatalf("expected 1 fork, got %d", len(forks))
+	}
+	assertEqual(t, "FullName", "someone/myrepo", forks[0].FullName)
+	assertEqual(t, "SourceName", "mygroup/myrepo", forks[0].SourceName)
+}
+
+func TestGitLabFetchActivity(t *testing.T) {
+	since := time.Now().Add(-7 * 24 * time.Hour)
+	recent := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/mygroup%2Fmyrepo/repository/commits", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("since") == "" {
+			t.Error("expected since parameter")
+		}
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"id": "c1", "author_email": "a@example.com", "committed_date": recent.Format(time.RFC3339)},
+			{"id": "c2", "author_email": "A@example.com", "committed_date": recent.Add(-time.Hour).Format(time.RFC3339)},
+			{"id": "c3", "author_email": "b@example.com", "committed_date": recent.Add(-2 * time.Hour).Format(time.RFC3339)},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGitLabForge(srv.URL, "", nil)
+
+	a, err := f.FetchActivity(context.Background(), "mygroup", "myrepo", since)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqualInt(t, "TotalCommits", 3, a.TotalCommits)
+	assertEqualInt(t, "Committers", 2, a.Committers)
+	assertEqual(t, "LastCommitAt", recent.Format(time.RFC3339), a.LastCommitAt.Format(time.RFC3339))
+}
+
+func TestGitLabListRepositoriesCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pages := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/groups/biggroup/projects", func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		if pages == 3 {
+			cancel()
+		}
+		// There is always a next page, so paging would never end on its own.
+		w.Header().Set("X-Next-Page", strconv.Itoa(pages+1))
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"path_with_namespace": "biggroup/a"},
+			{"path_with_namespace": "biggroup/b"},
+		})
+	})
+	mux.HandleFunc("GET /api/v4/users/biggroup/projects", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a cancelled listing should not fall back to the user endpoint")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGitLabForge(srv.URL, "", nil)
+	_, err := f.ListRepositories(ctx, "biggroup", ListOptions{PerPage: 2})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	assertEqualInt(t, "pages", 3, pages)
+}
+
+func TestGitLabRequestTimeout(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/slow%2Frepo", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newGitLabForge(srv.URL, "", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := f.FetchRepository(ctx, "slow", "repo")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request took %s after its deadline", elapsed)
+	}
+}
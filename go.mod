@@ -4,9 +4,12 @@ go 1.25.6
 
 require (
 	code.gitea.io/sdk/gitea v0.23.2
+	github.com/BurntSushi/toml v1.6.0
 	github.com/git-pkgs/purl v0.1.5
 	github.com/google/go-github/v82 v82.0.0
 	gitlab.com/gitlab-org/api/client-go v1.28.0
+	golang.org/x/time v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -22,5 +25,4 @@ require (
 	golang.org/x/crypto v0.39.0 // indirect
 	golang.org/x/oauth2 v0.34.0 // indirect
 	golang.org/x/sys v0.39.0 // indirect
-	golang.org/x/time v0.14.0 // indirect
 )
@@ -0,0 +1,196 @@
+package forges
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// GoImport describes where a Go import path's source lives, as announced by
+// the go-import and go-source meta tags its host serves.
+type GoImport struct {
+	Prefix  string `json:"prefix"`             // import path prefix the repository provides
+	VCS     string `json:"vcs"`                // usually "git"
+	RepoURL string `json:"repo_url"`           // repository root from go-import
+	HomeURL string `json:"home_url,omitempty"` // repository home page from go-source, if any
+}
+
+// ResolveGoImportPath finds the repository behind a Go import path such as
+// golang.org/x/tools or go.uber.org/zap/zapcore by fetching
+// https://<path>?go-get=1 and reading its meta tags. gopkg.in paths are
+// resolved locally to the GitHub repositories they redirect to.
+//
+// RepoURL is where the code is cloned from. Some hosts, like golang.org,
+// serve code from their own git servers and point go-source at a forge
+// mirror, which is then available in HomeURL.
+func ResolveGoImportPath(ctx context.Context, importPath string) (*GoImport, error) {
+	return resolveGoImportPath(ctx, http.DefaultClient, importPath)
+}
+
+func resolveGoImportPath(ctx context.Context, hc *http.Client, importPath string) (*GoImport, error) {
+	importPath = strings.Trim(strings.TrimSpace(importPath), "/")
+	if importPath == "" {
+		return nil, fmt.Errorf("empty import path")
+	}
+	if imp, ok := resolveGopkgIn(importPath); ok {
+		return imp, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+importPath+"?go-get=1", nil)
+	if err != nil {
+		return nil, err
+	}
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Like the go command, read meta tags even from error pages, since
+	// some hosts only serve them with a 404.
+	imp, err := parseGoImportMeta(io.LimitReader(resp.Body, 1<<20), importPath)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", importPath, err)
+	}
+	return imp, nil
+}
+
+// goImportPathFromURL turns an https:// or schemeless URL into the import
+// path it would be if it were one. Other URL forms can't be import paths.
+func goImportPathFromURL(rawURL string) (string, bool) {
+	p := strings.TrimSpace(rawURL)
+	if rest, ok := strings.CutPrefix(p, "https://"); ok {
+		p = rest
+	} else if rest, ok := strings.CutPrefix(p, "http://"); ok {
+		p = rest
+	}
+	if strings.Contains(p, "://") || strings.Contains(p, "@") {
+		return "", false
+	}
+	p, _, _ = strings.Cut(p, "#")
+	p, _, _ = strings.Cut(p, "?")
+	p = strings.Trim(p, "/")
+
+	host, _, _ := strings.Cut(p, "/")
+	if !strings.Contains(host, ".") {
+		return "", false
+	}
+	return p, true
+}
+
+// resolveGopkgIn maps gopkg.in/pkg.vN to github.com/go-pkg/pkg and
+// gopkg.in/user/pkg.vN to github.com/user/pkg.
+func resolveGopkgIn(importPath string) (*GoImport, bool) {
+	rest, ok := strings.CutPrefix(importPath, "gopkg.in/")
+	if !ok {
+		return nil, false
+	}
+	parts := strings.Split(rest, "/")
+
+	var user, pkg string
+	var n int
+	switch {
+	case gopkgInVersioned(parts[0]):
+		pkg, n = parts[0], 1
+	case len(parts) > 1 && gopkgInVersioned(parts[1]):
+		user, pkg, n = parts[0], parts[1], 2
+	default:
+		return nil, false
+	}
+	name := pkg[:strings.LastIndex(pkg, ".v")]
+	if user == "" {
+		user = "go-" + name
+	}
+	return &GoImport{
+		Prefix:  "gopkg.in/" + strings.Join(parts[:n], "/"),
+		VCS:     "git",
+		RepoURL: "https://github.com/" + user + "/" + name,
+		HomeURL: "https://github.com/" + user + "/" + name,
+	}, true
+}
+
+// gopkgInVersioned reports whether s has gopkg.in's .vN suffix.
+func gopkgInVersioned(s string) bool {
+	i := strings.LastIndex(s, ".v")
+	if i <= 0 || i+2 == len(s) {
+		return false
+	}
+	return strings.Trim(s[i+2:], "0123456789") == ""
+}
+
+// errNoGoImport is returned when a page has no go-import tag for the path.
+var errNoGoImport = errors.New("no go-import meta tag")
+
+// parseGoImportMeta reads go-import and go-source meta tags from an HTML
+// page's head, the same lenient way the go command does.
+func parseGoImportMeta(r io.Reader, importPath string) (*GoImport, error) {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var imp *GoImport
+	var home string
+	for {
+		t, err := d.RawToken()
+		if err != nil {
+			break
+		}
+		if e, ok := t.(xml.EndElement); ok && strings.EqualFold(e.Name.Local, "head") {
+			break
+		}
+		e, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if strings.EqualFold(e.Name.Local, "body") {
+			break
+		}
+		if !strings.EqualFold(e.Name.Local, "meta") {
+			continue
+		}
+
+		fields := strings.Fields(xmlAttr(e, "content"))
+		if len(fields) < 1 || !hasPathPrefix(importPath, fields[0]) {
+			continue
+		}
+		switch xmlAttr(e, "name") {
+		case "go-import":
+			// Module proxy entries carry no repository.
+			if imp == nil && len(fields) == 3 && fields[1] != "mod" {
+				imp = &GoImport{Prefix: fields[0], VCS: fields[1], RepoURL: fields[2]}
+			}
+		case "go-source":
+			if len(fields) >= 2 && fields[1] != "_" {
+				home = fields[1]
+			}
+		}
+	}
+
+	if imp == nil {
+		return nil, errNoGoImport
+	}
+	imp.HomeURL = strings.TrimSuffix(home, "/")
+	return imp, nil
+}
+
+func xmlAttr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if strings.EqualFold(a.Name.Local, name) {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// hasPathPrefix reports whether path is prefix or lies beneath it.
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
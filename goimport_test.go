@@ -0,0 +1,157 @@
+package forges
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseGoImportMeta(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html>
+<head>
+<meta http-equiv="Content-Type" content="text/html; charset=utf-8"/>
+<meta name="go-import" content="golang.org/x/tools mod https://proxy.golang.org">
+<meta name="go-import" content="golang.org/x/tools git https://go.googlesource.com/tools">
+<meta name="go-source" content="golang.org/x/tools https://github.com/golang/tools/ https://github.com/golang/tools/tree/master{/dir} https://github.com/golang/tools/blob/master{/dir}/{file}#L{line}">
+<meta name="go-import" content="golang.org/x/net git https://go.googlesource.com/net">
+</head>
+<body>
+<meta name="go-import" content="golang.org/x/tools git https://evil.example.com/tools">
+</body>
+</html>`
+
+	imp, err := parseGoImportMeta(strings.NewReader(page), "golang.org/x/tools/go/packages")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "Prefix", "golang.org/x/tools", imp.Prefix)
+	assertEqual(t, "VCS", "git", imp.VCS)
+	assertEqual(t, "RepoURL", "https://go.googlesource.com/tools", imp.RepoURL)
+	assertEqual(t, "HomeURL", "https://github.com/golang/tools", imp.HomeURL)
+
+	if _, err := parseGoImportMeta(strings.NewReader(page), "golang.org/x/toolsx"); err == nil {
+		t.Error("expected error for a path outside every prefix")
+	}
+}
+
+func TestResolveGopkgIn(t *testing.T) {
+	tests := []struct {
+		path, prefix, repo string
+	}{
+		{"gopkg.in/yaml.v3", "gopkg.in/yaml.v3", "https://github.com/go-yaml/yaml"},
+		{"gopkg.in/check.v1/sub", "gopkg.in/check.v1", "https://github.com/go-check/check"},
+		{"gopkg.in/src-d/go-git.v4", "gopkg.in/src-d/go-git.v4", "https://github.com/src-d/go-git"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			imp, ok := resolveGopkgIn(tt.path)
+			if !ok {
+				t.Fatal("expected gopkg.in path to resolve")
+			}
+			assertEqual(t, "Prefix", tt.prefix, imp.Prefix)
+			assertEqual(t, "RepoURL", tt.repo, imp.RepoURL)
+		})
+	}
+
+	for _, path := range []string{"gopkg.in/yaml", "gopkg.in/user/pkg", "golang.org/x/tools"} {
+		if _, ok := resolveGopkgIn(path); ok {
+			t.Errorf("%s: expected no match", path)
+		}
+	}
+}
+
+// goImportServer serves go-import meta tags for any path under its host.
+func goImportServer(t *testing.T, repoRoot, home string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("go-get") != "1" {
+			t.Errorf("expected go-get=1, got %q", r.URL.RawQuery)
+		}
+		prefix := r.Host + "/x/tools"
+		fmt.Fprintf(w, `<html><head>
+<meta name="go-import" content="%s git %s">
+<meta name="go-source" content="%s %s _ _">
+</head></html>`, prefix, repoRoot, prefix, home)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestResolveGoImportPath(t *testing.T) {
+	srv := goImportServer(t, "https://go.googlesource.com/tools", "https://github.com/golang/tools")
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	imp, err := resolveGoImportPath(context.Background(), srv.Client(), host+"/x/tools/cmd/stringer")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "Prefix", host+"/x/tools", imp.Prefix)
+	assertEqual(t, "RepoURL", "https://go.googlesource.com/tools", imp.RepoURL)
+	assertEqual(t, "HomeURL", "https://github.com/golang/tools", imp.HomeURL)
+}
+
+func TestClientFetchRepositoryGoImportPath(t *testing.T) {
+	srv := goImportServer(t, "https://go.googlesource.com/tools", "https://github.com/golang/tools")
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	mock := &mockForge{repo: &Repository{FullName: "golang/tools"}}
+	c := &Client{
+		forges:     map[string]Forge{"github.com": mock},
+		tokens:     make(map[string]string),
+		httpClient: srv.Client(),
+	}
+
+	repo, err := c.FetchRepository(context.Background(), host+"/x/tools")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "FullName", "golang/tools", repo.FullName)
+	assertEqual(t, "owner", "golang", mock.lastOwner)
+	assertEqual(t, "repo", "tools", mock.lastRepo)
+}
+
+func TestClientGoImportOnlyForFetchRepository(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	t.Cleanup(srv.Close)
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	c := NewClient(WithHTTPClient(srv.Client()))
+	if _, err := c.FetchTags(context.Background(), host+"/x/tools"); err == nil ||
+		!strings.Contains(err.Error(), "no forge registered") {
+		t.Fatalf("expected an unregistered domain error, got %v", err)
+	}
+	if _, err := c.ListIssues(context.Background(), host+"/x/tools", IssueListOptions{}); err == nil {
+		t.Fatal("expected an error")
+	}
+	assertEqualInt(t, "requests", 0, int(hits.Load()))
+
+	c.FetchRepository(context.Background(), host+"/x/tools")
+	assertEqualInt(t, "requests after FetchRepository", 1, int(hits.Load()))
+}
+
+func TestGoImportPathFromURL(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"go.uber.org/zap", "go.uber.org/zap", true},
+		{"https://golang.org/x/tools?tab=doc", "golang.org/x/tools", true},
+		{"git@example.com:o/r.git", "", false},
+		{"ssh://example.com/o/r", "", false},
+		{"localhost/o/r", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := goImportPathFromURL(tt.input)
+		assertEqualBool(t, tt.input+" ok", tt.ok, ok)
+		assertEqual(t, tt.input, tt.want, got)
+	}
+}
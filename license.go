@@ -0,0 +1,279 @@
+package forges
+
+import (
+	"embed"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// spdxLicenses maps lowercased license keys to SPDX identifiers. It covers
+// the SPDX identifiers themselves, the licensee keys GitLab reports (which
+// are mostly lowercased SPDX identifiers), and common informal spellings.
+//
+// Keys without a version qualifier, like gpl-3.0, map to the identifier
+// GitHub reports for the same license so both forges agree.
+var spdxLicenses = map[string]string{
+	"0bsd":                "0BSD",
+	"afl-3.0":             "AFL-3.0",
+	"agpl-3.0":            "AGPL-3.0",
+	"agpl-3.0-only":       "AGPL-3.0-only",
+	"agpl-3.0-or-later":   "AGPL-3.0-or-later",
+	"apache-1.1":          "Apache-1.1",
+	"apache-2.0":          "Apache-2.0",
+	"artistic-1.0":        "Artistic-1.0",
+	"artistic-2.0":        "Artistic-2.0",
+	"blueoak-1.0.0":       "BlueOak-1.0.0",
+	"bsd-2-clause":        "BSD-2-Clause",
+	"bsd-2-clause-patent": "BSD-2-Clause-Patent",
+	"bsd-3-clause":        "BSD-3-Clause",
+	"bsd-3-clause-clear":  "BSD-3-Clause-Clear",
+	"bsd-4-clause":        "BSD-4-Clause",
+	"bsl-1.0":             "BSL-1.0",
+	"cc-by-4.0":           "CC-BY-4.0",
+	"cc-by-nc-4.0":        "CC-BY-NC-4.0",
+	"cc-by-sa-4.0":        "CC-BY-SA-4.0",
+	"cc0-1.0":             "CC0-1.0",
+	"cddl-1.0":            "CDDL-1.0",
+	"cecill-2.1":          "CECILL-2.1",
+	"ecl-2.0":             "ECL-2.0",
+	"epl-1.0":             "EPL-1.0",
+	"epl-2.0":             "EPL-2.0",
+	"eupl-1.1":            "EUPL-1.1",
+	"eupl-1.2":            "EUPL-1.2",
+	"gfdl-1.3":            "GFDL-1.3",
+	"gpl-2.0":             "GPL-2.0",
+	"gpl-2.0-only":        "GPL-2.0-only",
+	"gpl-2.0-or-later":    "GPL-2.0-or-later",
+	"gpl-3.0":             "GPL-3.0",
+	"gpl-3.0-only":        "GPL-3.0-only",
+	"gpl-3.0-or-later":    "GPL-3.0-or-later",
+	"isc":                 "ISC",
+	"lgpl-2.1":            "LGPL-2.1",
+	"lgpl-2.1-only":       "LGPL-2.1-only",
+	"lgpl-2.1-or-later":   "LGPL-2.1-or-later",
+	"lgpl-3.0":            "LGPL-3.0",
+	"lgpl-3.0-only":       "LGPL-3.0-only",
+	"lgpl-3.0-or-later":   "LGPL-3.0-or-later",
+	"lppl-1.3c":           "LPPL-1.3c",
+	"mit":                 "MIT",
+	"mit-0":               "MIT-0",
+	"mpl-1.1":             "MPL-1.1",
+	"mpl-2.0":             "MPL-2.0",
+	"ms-pl":               "MS-PL",
+	"ms-rl":               "MS-RL",
+	"mulanpsl-2.0":        "MulanPSL-2.0",
+	"ncsa":                "NCSA",
+	"odbl-1.0":            "ODbL-1.0",
+	"ofl-1.1":             "OFL-1.1",
+	"openssl":             "OpenSSL",
+	"osl-3.0":             "OSL-3.0",
+	"postgresql":          "PostgreSQL",
+	"python-2.0":          "Python-2.0",
+	"unlicense":           "Unlicense",
+	"upl-1.0":             "UPL-1.0",
+	"vim":                 "Vim",
+	"wtfpl":               "WTFPL",
+	"x11":                 "X11",
+	"zlib":                "Zlib",
+
+	// Informal spellings.
+	"apache2":       "Apache-2.0",
+	"apache-2":      "Apache-2.0",
+	"apache 2.0":    "Apache-2.0",
+	"agplv3":        "AGPL-3.0",
+	"gplv2":         "GPL-2.0",
+	"gplv3":         "GPL-3.0",
+	"gpl-2.0+":      "GPL-2.0-or-later",
+	"gpl-3.0+":      "GPL-3.0-or-later",
+	"lgplv3":        "LGPL-3.0",
+	"lgpl-2.1+":     "LGPL-2.1-or-later",
+	"lgpl-3.0+":     "LGPL-3.0-or-later",
+	"mpl2":          "MPL-2.0",
+	"bsd-2":         "BSD-2-Clause",
+	"bsd-3":         "BSD-3-Clause",
+	"cc0":           "CC0-1.0",
+	"boost":         "BSL-1.0",
+	"the unlicense": "Unlicense",
+}
+
+// NormalizeLicense converts a forge's license key to an SPDX identifier.
+// Matching is case-insensitive. It returns "" for placeholders like
+// NOASSERTION or other, and for keys it doesn't recognize, except that
+// LicenseRef- identifiers are passed through unchanged.
+func NormalizeLicense(raw string) string {
+	raw = strings.TrimSpace(raw)
+	key := strings.ToLower(raw)
+	if id, ok := spdxLicenses[key]; ok {
+		return id
+	}
+	if strings.HasPrefix(key, "licenseref-") {
+		return raw
+	}
+	return ""
+}
+
+// spdxIDPattern matches the shape of an SPDX license identifier.
+var spdxIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.-]*\+?$`)
+
+// spdxLicense is NormalizeLicense for forges that already report SPDX
+// identifiers. Identifiers missing from the table are still valid SPDX, so
+// they're kept as reported rather than dropped. Only placeholders and values
+// that aren't shaped like an identifier map to "".
+func spdxLicense(raw string) string {
+	if id := NormalizeLicense(raw); id != "" {
+		return id
+	}
+	raw = strings.TrimSpace(raw)
+	switch strings.ToLower(raw) {
+	case "noassertion", "none", "other":
+		return ""
+	}
+	if spdxIDPattern.MatchString(raw) {
+		return raw
+	}
+	return ""
+}
+
+// licenseTemplateFS holds the license texts DetectLicense compares against,
+// one file per SPDX identifier.
+//
+//go:embed licenses/*.txt
+var licenseTemplateFS embed.FS
+
+// minLicenseConfidence is the lowest similarity DetectLicense will report
+// as a match.
+const minLicenseConfidence = 0.9
+
+// maxLicenseFiles caps how many candidate files are fetched per repository.
+const maxLicenseFiles = 3
+
+// licenseExclusions rules a template out when the text contains a phrase
+// from a near-identical license that isn't bundled, so an AGPL file isn't
+// reported as GPL.
+var licenseExclusions = map[string]string{
+	"GPL-3.0": "gnu affero general public license version 3",
+}
+
+var (
+	copyrightLine = regexp.MustCompile(`(?m)^\s*(copyright\s*(\(c\)|©|\d|<)|\(c\)|©).*$`)
+	nonWord       = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+type licenseTemplate struct {
+	id    string
+	words map[string]bool
+}
+
+var licenseTemplates = sync.OnceValue(func() []licenseTemplate {
+	entries, _ := licenseTemplateFS.ReadDir("licenses")
+	templates := make([]licenseTemplate, 0, len(entries))
+	for _, e := range entries {
+		data, err := licenseTemplateFS.ReadFile("licenses/" + e.Name())
+		if err != nil {
+			continue
+		}
+		templates = append(templates, licenseTemplate{
+			id:    strings.TrimSuffix(e.Name(), ".txt"),
+			words: wordSet(licenseWords(string(data))),
+		})
+	}
+	return templates
+})
+
+// licenseWords lowercases text and splits it into words, dropping copyright
+// statements and punctuation so only the license terms are compared.
+func licenseWords(text string) []string {
+	text = strings.ToLower(text)
+	text = copyrightLine.ReplaceAllString(text, "")
+	text = strings.ReplaceAll(text, "licence", "license")
+	return strings.Fields(nonWord.ReplaceAllString(text, " "))
+}
+
+func wordSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+// DetectLicense classifies license text against the bundled SPDX license
+// templates. It returns the best matching SPDX identifier and its similarity
+// (the Sørensen–Dice coefficient of the two word sets, from 0 to 1), or ""
+// and 0 when nothing matches with at least 0.9 similarity.
+func DetectLicense(text string) (string, float64) {
+	words := licenseWords(text)
+	if len(words) == 0 {
+		return "", 0
+	}
+	joined := strings.Join(words, " ")
+	set := wordSet(words)
+
+	var best string
+	var bestScore float64
+	for _, t := range licenseTemplates() {
+		if phrase, ok := licenseExclusions[t.id]; ok && strings.Contains(joined, phrase) {
+			continue
+		}
+		shared := 0
+		for w := range set {
+			if t.words[w] {
+				shared++
+			}
+		}
+		score := 2 * float64(shared) / float64(len(set)+len(t.words))
+		if score > bestScore {
+			best, bestScore = t.id, score
+		}
+	}
+	if bestScore < minLicenseConfidence {
+		return "", 0
+	}
+	return best, bestScore
+}
+
+// licenseFileCandidates picks the LICENSE*, LICENCE* and COPYING* files out
+// of a directory listing, shortest name first so LICENSE beats
+// LICENSE-THIRD-PARTY.
+func licenseFileCandidates(names []string) []string {
+	var candidates []string
+	for _, name := range names {
+		lower := strings.ToLower(path.Base(name))
+		if strings.HasPrefix(lower, "license") || strings.HasPrefix(lower, "licence") ||
+			strings.HasPrefix(lower, "copying") || strings.HasPrefix(lower, "unlicense") {
+			candidates = append(candidates, name)
+		}
+	}
+	slices.SortFunc(candidates, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(a) - len(b)
+		}
+		return strings.Compare(a, b)
+	})
+	return truncate(candidates, maxLicenseFiles)
+}
+
+// detectRepositoryLicense reads the license files among names with read and
+// records the best match on result. It leaves result alone when none match.
+func detectRepositoryLicense(result *Repository, names []string, read func(name string) (string, error)) error {
+	var best string
+	var bestScore float64
+	for _, name := range licenseFileCandidates(names) {
+		text, err := read(name)
+		if err != nil {
+			return err
+		}
+		if id, score := DetectLicense(text); score > bestScore {
+			best, bestScore = id, score
+		}
+	}
+	if best != "" {
+		result.License = best
+		result.LicenseConfidence = bestScore
+		result.FieldsPopulated |= FieldLicense
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package forges
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeLicense(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"MIT", "MIT"},
+		{"mit", "MIT"},
+		{"apache-2.0", "Apache-2.0"},
+		{" Apache-2.0 ", "Apache-2.0"},
+		{"gpl-3.0", "GPL-3.0"},
+		{"GPL-3.0-or-later", "GPL-3.0-or-later"},
+		{"gpl-3.0+", "GPL-3.0-or-later"},
+		{"bsd-3-clause", "BSD-3-Clause"},
+		{"unlicense", "Unlicense"},
+		{"postgresql", "PostgreSQL"},
+		{"mpl-1.1", "MPL-1.1"},
+		{"LicenseRef-Proprietary", "LicenseRef-Proprietary"},
+		{"NOASSERTION", ""},
+		{"other", ""},
+		{"", ""},
+		{"made-up-1.0", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			assertEqual(t, "NormalizeLicense", tt.want, NormalizeLicense(tt.raw))
+		})
+	}
+}
+
+func TestSPDXLicense(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"MIT", "MIT"},
+		{"gpl-3.0+", "GPL-3.0-or-later"},
+		{"Sleepycat", "Sleepycat"},
+		{"CC-BY-ND-4.0", "CC-BY-ND-4.0"},
+		{"Artistic-1.0-Perl", "Artistic-1.0-Perl"},
+		{"GPL-2.0+", "GPL-2.0-or-later"},
+		{"NOASSERTION", ""},
+		{"other", ""},
+		{"Apache License 2.0", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			assertEqual(t, "spdxLicense", tt.want, spdxLicense(tt.raw))
+		})
+	}
+}
+
+const mitLicenseText = `The MIT License (MIT)
+
+Copyright (c) 2014 Jane Doe <jane@example.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+`
+
+const bsd3LicenseText = `Copyright 2009 The Go Authors.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are
+met:
+
+   * Redistributions of source code must retain the above copyright
+notice, this list of conditions and the following disclaimer.
+   * Redistributions in binary form must reproduce the above
+copyright notice, this list of conditions and the following disclaimer
+in the documentation and/or other materials provided with the
+distribution.
+   * Neither the name of Google LLC nor the names of its
+contributors may be used to endorse or promote products derived from
+this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+"AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+(INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+`
+
+func TestDetectLicense(t *testing.T) {
+	gpl3, err := licenseTemplateFS.ReadFile("licenses/GPL-3.0.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	agpl := strings.Replace(string(gpl3), "GNU GENERAL PUBLIC LICENSE\n                       Version 3, 29 June 2007",
+		"GNU AFFERO GENERAL PUBLIC LICENSE\n                       Version 3, 19 November 2007", 1)
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"mit", mitLicenseText, "MIT"},
+		{"bsd-3 with named holder", bsd3LicenseText, "BSD-3-Clause"},
+		{"gpl-3 template", string(gpl3), "GPL-3.0"},
+		{"agpl is not gpl", agpl, ""},
+		{"readme", "# hello\n\nThis project does things. See the docs for more.", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, score := DetectLicense(tt.text)
+			assertEqual(t, "DetectLicense", tt.want, got)
+			if tt.want != "" && score < minLicenseConfidence {
+				t.Errorf("confidence %v below threshold", score)
+			}
+			if tt.want == "" && score != 0 {
+				t.Errorf("expected 0 confidence without a match, got %v", score)
+			}
+		})
+	}
+}
+
+func TestDetectLicensePrefersBSD2OverBSD3(t *testing.T) {
+	bsd2 := strings.Replace(bsd3LicenseText, `   * Neither the name of Google LLC nor the names of its
+contributors may be used to endorse or promote products derived from
+this software without specific prior written permission.
+`, "", 1)
+	got, _ := DetectLicense(bsd2)
+	assertEqual(t, "DetectLicense", "BSD-2-Clause", got)
+}
+
+func TestLicenseFileCandidates(t *testing.T) {
+	got := licenseFileCandidates([]string{
+		"README.md", "LICENSE-THIRD-PARTY", "go.mod", "COPYING", "LICENSE.md", "LICENSE", "licence.txt",
+	})
+	assertSliceEqual(t, "candidates", []string{"COPYING", "LICENSE", "LICENSE.md"}, got)
+}
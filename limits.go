@@ -0,0 +1,50 @@
+package forges
+
+import (
+	"math"
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimitTransport holds each request back until the limiter allows it.
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	limiter *rate.Limiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// newRateLimiter allows rps requests per second in bursts of up to burst,
+// which defaults to rps rounded up.
+func newRateLimiter(rps float64, burst int) *rate.Limiter {
+	if burst <= 0 {
+		burst = max(1, int(math.Ceil(rps)))
+	}
+	return rate.NewLimiter(rate.Limit(rps), burst)
+}
+
+// domainHTTPClient returns the client's HTTP client with the rate limit and
+// timeout set for domain applied, or the client itself when there are none.
+func (c *Client) domainHTTPClient(domain string) *http.Client {
+	limiter, limited := c.rateLimits[domain]
+	timeout, timed := c.timeouts[domain]
+	if !limited && !timed {
+		return c.httpClient
+	}
+	client := wrapTransport(c.httpClient, func(base http.RoundTripper) http.RoundTripper {
+		if !limited {
+			return base
+		}
+		return &rateLimitTransport{base: base, limiter: limiter}
+	})
+	if timed {
+		client.Timeout = timeout
+	}
+	return client
+}
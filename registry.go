@@ -0,0 +1,122 @@
+package forges
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ForgeConfig is what a ForgeFactory needs to build the backend for one
+// registered domain.
+type ForgeConfig struct {
+	Domain  string // the registration key: host, any non-default port and path prefix
+	BaseURL string // root of the instance's API, without a version path
+	// Token is the static API token, or "" when there is none or the
+	// HTTP client already authenticates requests.
+	Token      string
+	HTTPClient *http.Client // nil means http.DefaultClient
+	// Version is the instance's version when detection read it, or "".
+	Version string
+	// Retrying reports that HTTPClient retries failed requests following
+	// the Client's RetryPolicy, so backends shouldn't retry on top of it.
+	Retrying bool
+}
+
+// ForgeFactory builds the backend for a domain running a forge type.
+type ForgeFactory func(cfg ForgeConfig) (Forge, error)
+
+// ForgeDetector reports whether the instance at baseURL runs a forge type,
+// typically by probing an endpoint only that software serves. Probes should
+// go through hc, which carries the Client's transport, rate limits and
+// timeouts.
+type ForgeDetector func(ctx context.Context, hc *http.Client, baseURL string) bool
+
+type forgeRegistration struct {
+	factory  ForgeFactory
+	detector ForgeDetector
+}
+
+var (
+	forgeRegistryMu sync.RWMutex
+	forgeRegistry   = map[ForgeType]forgeRegistration{}
+	// forgeDetectOrder lists third-party types with detectors in the order
+	// they were registered.
+	forgeDetectOrder []ForgeType
+)
+
+// RegisterForgeType makes a forge type available to every Client, so
+// domains running it can be registered with WithForge or found by
+// RegisterDomain, and URLs on them are routed to the backend factory
+// builds. detector may be nil for types that can't be recognized remotely;
+// otherwise DetectForgeType tries it, in registration order, when none of
+// the built-in forges match. Registering a built-in type replaces its
+// factory.
+func RegisterForgeType(ft ForgeType, factory ForgeFactory, detector ForgeDetector) {
+	forgeRegistryMu.Lock()
+	defer forgeRegistryMu.Unlock()
+	if detector != nil && !slices.Contains(forgeDetectOrder, ft) {
+		forgeDetectOrder = append(forgeDetectOrder, ft)
+	}
+	forgeRegistry[ft] = forgeRegistration{factory: factory, detector: detector}
+}
+
+func lookupForgeFactory(ft ForgeType) (ForgeFactory, bool) {
+	forgeRegistryMu.RLock()
+	defer forgeRegistryMu.RUnlock()
+	r, ok := forgeRegistry[ft]
+	return r.factory, ok
+}
+
+// detectRegisteredForgeType runs the detectors of third-party forge types,
+// passing them hc.
+func detectRegisteredForgeType(ctx context.Context, hc *http.Client, baseURL string) (ForgeType, bool) {
+	forgeRegistryMu.RLock()
+	types := make([]ForgeType, 0, len(forgeDetectOrder))
+	detectors := make([]ForgeDetector, 0, len(forgeDetectOrder))
+	for _, ft := range forgeDetectOrder {
+		if d := forgeRegistry[ft].detector; d != nil {
+			types = append(types, ft)
+			detectors = append(detectors, d)
+		}
+	}
+	forgeRegistryMu.RUnlock()
+
+	for i, detect := range detectors {
+		if detect(ctx, hc, baseURL) {
+			return types[i], true
+		}
+	}
+	return Unknown, false
+}
+
+func init() {
+	// The built-in forges are detected by detectForgeTypeAt itself, so they
+	// register without detectors.
+	RegisterForgeType(GitHub, func(cfg ForgeConfig) (Forge, error) {
+		if cfg.BaseURL == "https://github.com" {
+			return newGitHubForge(cfg.Token, cfg.HTTPClient), nil
+		}
+		return newGitHubForgeWithBase(cfg.BaseURL, cfg.Token, cfg.HTTPClient), nil
+	}, nil)
+	RegisterForgeType(GitLab, func(cfg ForgeConfig) (Forge, error) {
+		if cfg.Retrying {
+			return newGitLabForge(cfg.BaseURL, cfg.Token, cfg.HTTPClient, gitlab.WithoutRetries()), nil
+		}
+		return newGitLabForge(cfg.BaseURL, cfg.Token, cfg.HTTPClient), nil
+	}, nil)
+	gitea := func(cfg ForgeConfig) (Forge, error) {
+		f := newGiteaForge(cfg.BaseURL, cfg.Token, cfg.HTTPClient)
+		if cfg.Version != "" {
+			f.version, f.versionKnown = cfg.Version, true
+		}
+		return f, nil
+	}
+	RegisterForgeType(Gitea, gitea, nil)
+	RegisterForgeType(Forgejo, gitea, nil)
+	RegisterForgeType(Bitbucket, func(cfg ForgeConfig) (Forge, error) {
+		return newBitbucketForge(cfg.Token, cfg.HTTPClient), nil
+	}, nil)
+}
@@ -0,0 +1,96 @@
+package forges
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const gerrit ForgeType = "gerrit"
+
+// registerTestForgeType registers ft for the duration of a test.
+func registerTestForgeType(t *testing.T, ft ForgeType, factory ForgeFactory, detector ForgeDetector) {
+	t.Helper()
+	RegisterForgeType(ft, factory, detector)
+	t.Cleanup(func() {
+		forgeRegistryMu.Lock()
+		defer forgeRegistryMu.Unlock()
+		delete(forgeRegistry, ft)
+		forgeDetectOrder = slices.DeleteFunc(forgeDetectOrder, func(o ForgeType) bool { return o == ft })
+	})
+}
+
+func TestRegisterForgeType(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /config/server/version", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, ")]}'\n\"3.9.1\"")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var configs []ForgeConfig
+	var probedWith *http.Client
+	mock := &mockForge{repo: &Repository{FullName: "team/repo"}}
+	registerTestForgeType(t, gerrit,
+		func(cfg ForgeConfig) (Forge, error) {
+			configs = append(configs, cfg)
+			return mock, nil
+		},
+		func(ctx context.Context, hc *http.Client, baseURL string) bool {
+			probedWith = hc
+			ok, err := probeURL(ctx, hc, baseURL+"/config/server/version")
+			return err == nil && ok
+		},
+	)
+
+	c := NewClient(
+		WithForge(srv.URL, gerrit, "secret"),
+		WithInsecureHTTP("127.0.0.1"),
+	)
+	repo, err := c.FetchRepository(context.Background(), srv.URL+"/team/repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "FullName", "team/repo", repo.FullName)
+	if len(configs) != 1 {
+		t.Fatalf("expected 1 factory call, got %d", len(configs))
+	}
+	assertEqual(t, "Domain", strings.TrimPrefix(srv.URL, "http://"), configs[0].Domain)
+	assertEqual(t, "BaseURL", srv.URL, configs[0].BaseURL)
+	assertEqual(t, "Token", "secret", configs[0].Token)
+
+	ft, err := detectForgeTypeAt(context.Background(), http.DefaultClient, srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "detected", string(gerrit), string(ft))
+
+	// The detector probes through the client's HTTP client.
+	tr := &countingTransport{}
+	c = NewClient(WithHTTPClient(&http.Client{Transport: tr}), WithInsecureHTTP("127.0.0.1"))
+	if err := c.RegisterDomain(context.Background(), srv.URL, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if probedWith == nil || probedWith.Transport != tr {
+		t.Error("expected the detector to get the client's HTTP client")
+	}
+	f, err := c.ForgeFor(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f != mock {
+		t.Error("expected RegisterDomain to use the registered factory")
+	}
+	assertEqual(t, "type", string(gerrit), string(c.forgeTypeFor(registrationKey(srv.URL))))
+}
+
+func TestUnregisteredForgeType(t *testing.T) {
+	c := NewClient(WithForge("phab.example.com", "phabricator", ""))
+	if _, err := c.ForgeFor("phab.example.com"); err == nil {
+		t.Error("expected error for a forge type without a factory")
+	}
+}
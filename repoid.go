@@ -0,0 +1,125 @@
+package forges
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RepoID identifies a repository independently of how its URL was spelled.
+// It is comparable, so it works as a map key. Build one with ParseRepoID or
+// Client.RepoID rather than by hand, so the canonicalization rules apply.
+type RepoID struct {
+	Domain string
+	Owner  string // may contain slashes for GitLab subgroups
+	Name   string
+}
+
+// String returns the ID as domain/owner/name.
+func (id RepoID) String() string {
+	return id.Domain + "/" + id.Owner + "/" + id.Name
+}
+
+// URL returns the canonical https:// URL for the repository.
+func (id RepoID) URL() string {
+	return "https://" + id.String()
+}
+
+// knownForgeTypes lists the forge software on public hosts, so URLs on them
+// can be canonicalized without a Client.
+var knownForgeTypes = map[string]ForgeType{
+	"github.com":    GitHub,
+	"gitlab.com":    GitLab,
+	"codeberg.org":  Gitea,
+	"bitbucket.org": Bitbucket,
+}
+
+// hostAliases maps alternative hostnames of public forges, such as their
+// SSH-over-443 endpoints, to the canonical host.
+var hostAliases = map[string]string{
+	"ssh.github.com":       "github.com",
+	"altssh.gitlab.com":    "gitlab.com",
+	"altssh.bitbucket.org": "bitbucket.org",
+}
+
+// ParseRepoID parses any URL form ParseRepoURL accepts into a canonical
+// RepoID:
+//
+//   - the host is lowercased, with any www. prefix and trailing dot removed
+//     and known aliases like ssh.github.com replaced by the main host
+//   - owner and name are lowercased on forges that treat them
+//     case-insensitively (GitHub, GitLab, Gitea and Bitbucket); on other
+//     hosts their case is kept
+//   - on GitLab the owner is the full namespace, including subgroups, and
+//     everything from the /-/ separator on is dropped
+//
+// So GitHub.com/Owner/Repo, www.github.com/owner/repo.git/ and
+// git@github.com:owner/repo all give the same RepoID.
+func ParseRepoID(rawURL string) (RepoID, error) {
+	host, _, path, err := splitRepoURL(rawURL)
+	if err != nil {
+		return RepoID{}, err
+	}
+	domain := canonicalHost(host)
+	return repoIDFromPath(domain, path, knownForgeType(domain))
+}
+
+// CanonicalRepoURL returns the canonical https:// URL for a repository URL
+// in any form ParseRepoURL accepts. See ParseRepoID for the rules applied.
+func CanonicalRepoURL(rawURL string) (string, error) {
+	id, err := ParseRepoID(rawURL)
+	if err != nil {
+		return "", err
+	}
+	return id.URL(), nil
+}
+
+func knownForgeType(domain string) ForgeType {
+	if ft, ok := knownForgeTypes[domain]; ok {
+		return ft
+	}
+	return Unknown
+}
+
+func canonicalHost(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	host = strings.TrimPrefix(host, "www.")
+	if canonical, ok := hostAliases[host]; ok {
+		return canonical
+	}
+	return host
+}
+
+// repoIDFromPath builds the RepoID for a URL path on a domain running the
+// given forge software.
+func repoIDFromPath(domain, path string, ft ForgeType) (RepoID, error) {
+	var owner, name string
+	var err error
+	if ft == GitLab {
+		owner, name, err = splitGitLabPath(path)
+	} else {
+		_, owner, name, err = splitOwnerRepo(domain, path)
+	}
+	if err != nil {
+		return RepoID{}, err
+	}
+
+	if ft != Unknown {
+		owner, name = strings.ToLower(owner), strings.ToLower(name)
+	}
+	return RepoID{Domain: domain, Owner: owner, Name: name}, nil
+}
+
+// splitGitLabPath splits a GitLab URL path into the full namespace and the
+// project name. Project pages put a /-/ between the project and the rest,
+// so any path without one is taken to be the project path itself.
+func splitGitLabPath(path string) (string, string, error) {
+	if i := strings.Index(path, "/-/"); i >= 0 {
+		path = path[:i]
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	i := strings.LastIndex(path, "/")
+	if i <= 0 || i == len(path)-1 {
+		return "", "", fmt.Errorf("URL path must contain namespace/project, got %q", path)
+	}
+	return path[:i], path[i+1:], nil
+}
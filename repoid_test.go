@@ -0,0 +1,116 @@
+package forges
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseRepoID(t *testing.T) {
+	tests := []struct {
+		input string
+		want  RepoID
+	}{
+		{"GitHub.com/Owner/Repo", RepoID{"github.com", "owner", "repo"}},
+		{"github.com/owner/repo.git", RepoID{"github.com", "owner", "repo"}},
+		{"https://www.github.com/owner/repo/", RepoID{"github.com", "owner", "repo"}},
+		{"git@github.com:owner/repo", RepoID{"github.com", "owner", "repo"}},
+		{"ssh://git@ssh.github.com:443/Owner/Repo.git", RepoID{"github.com", "owner", "repo"}},
+		{"https://github.com/owner/repo/tree/main", RepoID{"github.com", "owner", "repo"}},
+		{"https://GitLab.com/Group/Sub/Project/-/tree/main", RepoID{"gitlab.com", "group/sub", "project"}},
+		{"git@gitlab.com:group/sub/project.git", RepoID{"gitlab.com", "group/sub", "project"}},
+		{"https://codeberg.org/Forgejo/Forgejo", RepoID{"codeberg.org", "forgejo", "forgejo"}},
+		{"bitbucket:Atlassian/Repo", RepoID{"bitbucket.org", "atlassian", "repo"}},
+		{"https://git.example.org./Owner/Repo", RepoID{"git.example.org", "Owner", "Repo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseRepoID(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseRepoID("https://gitlab.com/just-group"); err == nil {
+		t.Error("expected error for GitLab URL without a project")
+	}
+}
+
+func TestCanonicalRepoURL(t *testing.T) {
+	got, err := CanonicalRepoURL("git@GitHub.com:Owner/Repo.git")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "CanonicalRepoURL", "https://github.com/owner/repo", got)
+}
+
+func TestClientRepoIDUsesRegisteredForgeType(t *testing.T) {
+	c := &Client{
+		forges: map[string]Forge{"gitlab.example.com": &gitLabForge{}},
+		tokens: make(map[string]string),
+	}
+	id, err := c.RepoID("https://gitlab.example.com/Team/Infra/Tool/-/merge_requests/3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := RepoID{"gitlab.example.com", "team/infra", "tool"}
+	if id != want {
+		t.Errorf("got %+v, want %+v", id, want)
+	}
+}
+
+func TestClientFetchRepositoriesDeduplicates(t *testing.T) {
+	mock := &mockForge{repo: &Repository{FullName: "owner/repo"}}
+	c := &Client{
+		forges: map[string]Forge{"github.com": mock},
+		tokens: make(map[string]string),
+	}
+
+	repos, errs := c.FetchRepositories(context.Background(), []string{
+		"GitHub.com/Owner/Repo",
+		"github.com/owner/repo.git",
+		"www.github.com/owner/repo/",
+		"git@github.com:owner/repo",
+		"not a url",
+	})
+
+	assertEqualInt(t, "fetches", 1, mock.fetches)
+	assertEqualInt(t, "repos", 1, len(repos))
+	if repos[RepoID{"github.com", "owner", "repo"}] == nil {
+		t.Error("expected repository keyed by canonical RepoID")
+	}
+	assertEqualInt(t, "errs", 1, len(errs))
+	if errs["not a url"] == nil {
+		t.Error("expected error for unparseable URL")
+	}
+}
+
+func TestClientFetchRepositoriesSubgroups(t *testing.T) {
+	mock := &mockForge{repoMap: map[string]*Repository{
+		"group/sub/project": {FullName: "group/sub/project"},
+		"group/sub":         {FullName: "group/sub"},
+	}}
+	c := &Client{
+		forges: map[string]Forge{"gitlab.com": mock},
+		tokens: make(map[string]string),
+	}
+	const u = "https://gitlab.com/group/sub/project"
+
+	repos, errs := c.FetchRepositories(context.Background(), []string{u})
+	assertEqualInt(t, "errs", 0, len(errs))
+	batch := repos[RepoID{"gitlab.com", "group/sub", "project"}]
+	if batch == nil {
+		t.Fatal("expected repository keyed by its full namespace")
+	}
+
+	// The single fetch resolves the URL to the same project.
+	single, err := c.FetchRepository(context.Background(), u)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "batch", "group/sub/project", batch.FullName)
+	assertEqual(t, "single", batch.FullName, single.FullName)
+}
@@ -0,0 +1,267 @@
+package forges
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// repoShorthands maps npm-style shorthand prefixes, as in github:owner/repo,
+// to the domain they stand for.
+var repoShorthands = map[string]string{
+	"github":    "github.com",
+	"gitlab":    "gitlab.com",
+	"bitbucket": "bitbucket.org",
+}
+
+// ParseRepoURL extracts the domain, owner, and repo from a repository URL.
+// It accepts:
+//
+//   - https:// and http:// URLs, and schemeless ones like github.com/o/r
+//   - ssh:// and git:// URLs, with any user and port
+//   - git+https:// and git+ssh:// URLs from package manifests
+//   - scp-style [user@]host:owner/repo SSH URLs
+//   - npm-style github:, gitlab: and bitbucket: shorthands
+//
+// Users and ports never end up in the domain. .git suffixes, fragments and
+// path segments after owner/repo are stripped.
+func ParseRepoURL(rawURL string) (domain, owner, repo string, err error) {
+	domain, _, path, err := splitRepoURL(rawURL)
+	if err != nil {
+		return "", "", "", err
+	}
+	return splitOwnerRepo(domain, path)
+}
+
+// splitRepoURL parses any of the forms ParseRepoURL accepts into the host,
+// the port of http(s) URLs that give a non-default one, and the unescaped
+// path after them. SSH ports are dropped, since they never match the web
+// port a forge is registered under.
+func splitRepoURL(rawURL string) (domain, port, path string, err error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", "", "", fmt.Errorf("empty URL")
+	}
+
+	if prefix, rest, ok := strings.Cut(rawURL, ":"); ok && !strings.HasPrefix(rest, "//") {
+		if domain, ok := repoShorthands[strings.ToLower(prefix)]; ok {
+			return domain, "", stripFragment(rest), nil
+		}
+	}
+
+	rawURL = strings.TrimPrefix(rawURL, "git+")
+
+	if !strings.Contains(rawURL, "://") {
+		if host, path, ok := splitSCP(rawURL); ok {
+			return host, "", stripFragment(path), nil
+		}
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid URL: %w", err)
+	}
+	switch u.Scheme {
+	case "https", "http", "ssh", "git":
+	default:
+		return "", "", "", fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	domain = u.Hostname()
+	if domain == "" {
+		return "", "", "", fmt.Errorf("URL has no host: %q", rawURL)
+	}
+	if u.Scheme == "https" || u.Scheme == "http" {
+		port = webPort(u)
+	}
+	return domain, port, u.Path, nil
+}
+
+// splitSCP splits an scp-style [user@]host:path URL. Without a user,
+// host:port/path is read as a schemeless URL with a port instead, which is
+// what a colon followed by digits almost always means.
+func splitSCP(s string) (host, path string, ok bool) {
+	hostPart, path, found := strings.Cut(s, ":")
+	if !found || strings.Contains(hostPart, "/") {
+		return "", "", false
+	}
+	host = hostPart
+	if i := strings.LastIndex(hostPart, "@"); i >= 0 {
+		host = hostPart[i+1:]
+	} else {
+		port, _, _ := strings.Cut(path, "/")
+		if port == "" || strings.Trim(port, "0123456789") == "" {
+			return "", "", false
+		}
+	}
+	if host == "" {
+		return "", "", false
+	}
+	return host, path, true
+}
+
+func stripFragment(s string) string {
+	s, _, _ = strings.Cut(s, "#")
+	return s
+}
+
+func splitOwnerRepo(domain, path string) (string, string, string, error) {
+	path = strings.TrimSuffix(path, ".git")
+	path = strings.Trim(path, "/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 {
+		return "", "", "", fmt.Errorf("URL path must contain owner/repo, got %q", path)
+	}
+	return domain, parts[0], parts[1], nil
+}
+
+// LocationKind identifies what a web UI URL inside a repository points at.
+type LocationKind string
+
+const (
+	LocationRepo        LocationKind = "repo"
+	LocationTree        LocationKind = "tree"
+	LocationBlob        LocationKind = "blob"
+	LocationCommit      LocationKind = "commit"
+	LocationTag         LocationKind = "tag"
+	LocationRelease     LocationKind = "release"
+	LocationIssue       LocationKind = "issue"
+	LocationPullRequest LocationKind = "pull_request"
+)
+
+// RepoLocation is a repository plus the place inside it a URL points at.
+type RepoLocation struct {
+	Domain string
+	Owner  string // may contain slashes for GitLab subgroups
+	Repo   string
+	Kind   LocationKind
+	Ref    string // branch, tag or commit SHA
+	Path   string // file or directory within the repository
+	Number int    // issue or pull request number
+}
+
+// ParseRepoLocation parses a link into a repository's web UI, such as
+// https://github.com/o/r/tree/main/pkg or
+// https://gitlab.com/g/p/-/blob/v1/README.md, using the URL layouts of
+// GitHub, GitLab, Gitea and Bitbucket. Links it doesn't recognize inside the
+// repository come back as LocationRepo.
+//
+// Branch names containing slashes can't be told apart from paths unless the
+// slash is escaped, so Ref holds only the first segment. Gitea and Bitbucket
+// link files and directories the same way, so their source links are
+// always LocationTree even when Path names a file.
+func ParseRepoLocation(rawURL string) (*RepoLocation, error) {
+	domain, owner, repo, err := ParseRepoURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	loc := &RepoLocation{Domain: domain, Owner: owner, Repo: repo, Kind: LocationRepo}
+
+	rawURL = strings.TrimPrefix(strings.TrimSpace(rawURL), "git+")
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		// Shorthands and SSH URLs only ever name a repository.
+		return loc, nil
+	}
+
+	var segs []string
+	for _, s := range strings.Split(strings.Trim(u.EscapedPath(), "/"), "/") {
+		if s, err := url.PathUnescape(s); err == nil {
+			segs = append(segs, s)
+		}
+	}
+
+	// GitLab separates the project path from the rest with "-", which
+	// also gives the full subgroup path.
+	if i := slices.Index(segs, "-"); i >= 2 {
+		loc.Owner = strings.Join(segs[:i-1], "/")
+		loc.Repo = segs[i-1]
+		parseLocationSegments(loc, segs[i+1:])
+		return loc, nil
+	}
+	if len(segs) > 2 {
+		parseLocationSegments(loc, segs[2:])
+	}
+	return loc, nil
+}
+
+// parseLocationSegments fills loc from the path segments after owner/repo
+// (or after GitLab's "-").
+func parseLocationSegments(loc *RepoLocation, segs []string) {
+	if len(segs) < 2 {
+		return
+	}
+	rest := func(n int) string {
+		if len(segs) <= n {
+			return ""
+		}
+		return strings.Join(segs[n:], "/")
+	}
+
+	switch segs[0] {
+	case "tree", "blob":
+		loc.Kind = LocationKind(segs[0])
+		loc.Ref, loc.Path = segs[1], rest(2)
+	case "commit":
+		loc.Kind, loc.Ref = LocationCommit, segs[1]
+	case "commits":
+		// A single commit on Bitbucket, but a ref's history on GitHub.
+		if isCommitSHA(segs[1]) {
+			loc.Kind, loc.Ref = LocationCommit, segs[1]
+		}
+	case "tags":
+		loc.Kind, loc.Ref = LocationTag, rest(1)
+	case "releases":
+		// GitHub and Gitea use releases/tag/<tag>, GitLab releases/<tag>.
+		switch {
+		case segs[1] == "latest":
+			loc.Kind = LocationRelease
+		case segs[1] == "tag" && len(segs) > 2:
+			loc.Kind, loc.Ref = LocationRelease, rest(2)
+		case segs[1] != "tag":
+			loc.Kind, loc.Ref = LocationRelease, rest(1)
+		}
+	case "issues":
+		setLocationNumber(loc, LocationIssue, segs[1])
+	case "pull", "pulls", "merge_requests", "pull-requests":
+		setLocationNumber(loc, LocationPullRequest, segs[1])
+	case "branch":
+		loc.Kind, loc.Ref = LocationTree, rest(1)
+	case "src", "raw":
+		loc.Kind = LocationTree
+		if segs[0] == "raw" {
+			loc.Kind = LocationBlob
+		}
+		// Gitea says which kind of ref follows; Bitbucket and GitLab's raw
+		// links go straight to the ref.
+		switch segs[1] {
+		case "branch", "tag", "commit":
+			if len(segs) > 2 {
+				loc.Ref, loc.Path = segs[2], rest(3)
+			}
+		default:
+			loc.Ref, loc.Path = segs[1], rest(2)
+		}
+	}
+}
+
+func setLocationNumber(loc *RepoLocation, kind LocationKind, s string) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return
+	}
+	loc.Kind, loc.Number = kind, n
+}
+
+// isCommitSHA reports whether s looks like an abbreviated or full commit SHA.
+func isCommitSHA(s string) bool {
+	if len(s) < 7 || len(s) > 64 {
+		return false
+	}
+	return strings.Trim(strings.ToLower(s), "0123456789abcdef") == ""
+}
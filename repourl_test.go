@@ -0,0 +1,109 @@
+package forges
+
+import "testing"
+
+func TestParseRepoLocation(t *testing.T) {
+	tests := []struct {
+		input string
+		want  RepoLocation
+	}{
+		{
+			input: "https://github.com/o/r",
+			want:  RepoLocation{Domain: "github.com", Owner: "o", Repo: "r", Kind: LocationRepo},
+		},
+		{
+			input: "https://github.com/o/r/tree/main/pkg",
+			want:  RepoLocation{Domain: "github.com", Owner: "o", Repo: "r", Kind: LocationTree, Ref: "main", Path: "pkg"},
+		},
+		{
+			input: "https://github.com/o/r/blob/feature%2Fx/cmd/main.go#L10",
+			want:  RepoLocation{Domain: "github.com", Owner: "o", Repo: "r", Kind: LocationBlob, Ref: "feature/x", Path: "cmd/main.go"},
+		},
+		{
+			input: "https://github.com/o/r/commit/4f2a9c1e",
+			want:  RepoLocation{Domain: "github.com", Owner: "o", Repo: "r", Kind: LocationCommit, Ref: "4f2a9c1e"},
+		},
+		{
+			input: "https://github.com/o/r/commits/main",
+			want:  RepoLocation{Domain: "github.com", Owner: "o", Repo: "r", Kind: LocationRepo},
+		},
+		{
+			input: "https://github.com/o/r/releases/tag/v1.2.0",
+			want:  RepoLocation{Domain: "github.com", Owner: "o", Repo: "r", Kind: LocationRelease, Ref: "v1.2.0"},
+		},
+		{
+			input: "https://github.com/o/r/issues/42",
+			want:  RepoLocation{Domain: "github.com", Owner: "o", Repo: "r", Kind: LocationIssue, Number: 42},
+		},
+		{
+			input: "https://github.com/o/r/pull/7/files",
+			want:  RepoLocation{Domain: "github.com", Owner: "o", Repo: "r", Kind: LocationPullRequest, Number: 7},
+		},
+		{
+			input: "https://github.com/o/r/issues/new",
+			want:  RepoLocation{Domain: "github.com", Owner: "o", Repo: "r", Kind: LocationRepo},
+		},
+		{
+			input: "https://gitlab.com/g/p/-/blob/v1/README.md",
+			want:  RepoLocation{Domain: "gitlab.com", Owner: "g", Repo: "p", Kind: LocationBlob, Ref: "v1", Path: "README.md"},
+		},
+		{
+			input: "https://gitlab.com/g/sub/p/-/merge_requests/12",
+			want:  RepoLocation{Domain: "gitlab.com", Owner: "g/sub", Repo: "p", Kind: LocationPullRequest, Number: 12},
+		},
+		{
+			input: "https://gitlab.com/g/p/-/tags/v2.0.0",
+			want:  RepoLocation{Domain: "gitlab.com", Owner: "g", Repo: "p", Kind: LocationTag, Ref: "v2.0.0"},
+		},
+		{
+			input: "https://gitlab.com/g/p/-/releases/v2.0.0",
+			want:  RepoLocation{Domain: "gitlab.com", Owner: "g", Repo: "p", Kind: LocationRelease, Ref: "v2.0.0"},
+		},
+		{
+			input: "https://codeberg.org/o/r/src/branch/main/x",
+			want:  RepoLocation{Domain: "codeberg.org", Owner: "o", Repo: "r", Kind: LocationTree, Ref: "main", Path: "x"},
+		},
+		{
+			input: "https://codeberg.org/o/r/raw/tag/v1/go.mod",
+			want:  RepoLocation{Domain: "codeberg.org", Owner: "o", Repo: "r", Kind: LocationBlob, Ref: "v1", Path: "go.mod"},
+		},
+		{
+			input: "https://codeberg.org/o/r/pulls/3",
+			want:  RepoLocation{Domain: "codeberg.org", Owner: "o", Repo: "r", Kind: LocationPullRequest, Number: 3},
+		},
+		{
+			input: "https://bitbucket.org/o/r/src/abc123/",
+			want:  RepoLocation{Domain: "bitbucket.org", Owner: "o", Repo: "r", Kind: LocationTree, Ref: "abc123"},
+		},
+		{
+			input: "https://bitbucket.org/o/r/commits/0123456789abcdef",
+			want:  RepoLocation{Domain: "bitbucket.org", Owner: "o", Repo: "r", Kind: LocationCommit, Ref: "0123456789abcdef"},
+		},
+		{
+			input: "https://bitbucket.org/o/r/pull-requests/5",
+			want:  RepoLocation{Domain: "bitbucket.org", Owner: "o", Repo: "r", Kind: LocationPullRequest, Number: 5},
+		},
+		{
+			input: "git@github.com:o/r.git",
+			want:  RepoLocation{Domain: "github.com", Owner: "o", Repo: "r", Kind: LocationRepo},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseRepoLocation(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRepoLocationInvalid(t *testing.T) {
+	if _, err := ParseRepoLocation("https://github.com/just-owner"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
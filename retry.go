@@ -0,0 +1,166 @@
+package forges
+
+import (
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy retries requests that fail transiently: connection errors
+// and responses with a retryable status code. Retries happen per HTTP
+// request, so a listing that hits a bad gateway on page 30 retries page 30
+// and carries on, rather than starting over. Zero numeric fields and a nil
+// RetryableStatus take their values from DefaultRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the most times a request is sent, the first included.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry. It doubles with
+	// each retry up to MaxBackoff, and a random part of up to half of it is
+	// taken off so that many clients don't retry in step. A Retry-After
+	// header on the response overrides it, still capped by MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// RetryableStatus lists the response codes worth retrying.
+	RetryableStatus []int
+	// RetryNonIdempotent retries POST and PATCH requests too. By default
+	// only GET, HEAD, OPTIONS, PUT and DELETE requests are retried, as they
+	// can't do harm if the failed attempt reached the server after all.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy makes up to four attempts, backing off from half a
+// second to at most 30 seconds, on 502, 503 and 504 responses and
+// connection errors, for idempotent requests only.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:     4,
+		InitialBackoff:  500 * time.Millisecond,
+		MaxBackoff:      30 * time.Second,
+		RetryableStatus: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	}
+}
+
+// withDefaults fills the zero fields of p from DefaultRetryPolicy.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	d := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = d.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = d.MaxBackoff
+	}
+	if p.RetryableStatus == nil {
+		p.RetryableStatus = d.RetryableStatus
+	}
+	return p
+}
+
+// backoff returns the wait before retry number n, counting from 0.
+func (p RetryPolicy) backoff(n int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := retryAfter(resp.Header, time.Now()); ok {
+			return min(wait, p.MaxBackoff)
+		}
+	}
+	wait := p.InitialBackoff
+	for range n {
+		wait *= 2
+		if wait >= p.MaxBackoff {
+			wait = p.MaxBackoff
+			break
+		}
+	}
+	return wait - rand.N(wait/2+1)
+}
+
+// retryAfter reads a Retry-After header, given either in seconds or as an
+// HTTP date, as the wait from now. A date in the past means no wait.
+func retryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	return max(t.Sub(now), 0), true
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryTransport resends requests as its policy allows.
+type retryTransport struct {
+	base   http.RoundTripper
+	policy RetryPolicy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.policy.RetryNonIdempotent && !idempotent(req.Method) {
+		return t.base.RoundTrip(req)
+	}
+	// A body that can't be replayed can only be sent once.
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		try := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			try = req.Clone(ctx)
+			try.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(try)
+		if ctx.Err() != nil || attempt >= t.policy.MaxAttempts {
+			return resp, err
+		}
+		if err == nil && !slices.Contains(t.policy.RetryableStatus, resp.StatusCode) {
+			return resp, nil
+		}
+
+		wait := t.policy.backoff(attempt-1, resp)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// retryClient wraps hc to retry requests following policy.
+func retryClient(hc *http.Client, policy RetryPolicy) *http.Client {
+	policy = policy.withDefaults()
+	return wrapTransport(hc, func(base http.RoundTripper) http.RoundTripper {
+		return &retryTransport{base: base, policy: policy}
+	})
+}
@@ -0,0 +1,293 @@
+package forges
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}.withDefaults()
+	for n, full := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		full *= time.Millisecond
+		for range 20 {
+			if got := p.backoff(n, nil); got < full/2 || got > full {
+				t.Fatalf("retry %d: backoff %s outside [%s, %s]", n, got, full/2, full)
+			}
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"0"}}}
+	if got := p.backoff(3, resp); got != 0 {
+		t.Errorf("expected Retry-After: 0 to be honored, got %s", got)
+	}
+	resp.Header.Set("Retry-After", "120")
+	if got := p.backoff(0, resp); got != time.Second {
+		t.Errorf("expected Retry-After to be capped at MaxBackoff, got %s", got)
+	}
+	resp.Header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	if got := p.backoff(3, resp); got != 0 {
+		t.Errorf("expected a past Retry-After date to mean no wait, got %s", got)
+	}
+	resp.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if got := p.backoff(0, resp); got != time.Second {
+		t.Errorf("expected a Retry-After date to be capped at MaxBackoff, got %s", got)
+	}
+}
+
+func TestRetryAfterDate(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	h := http.Header{"Retry-After": {now.Add(90 * time.Second).Format(http.TimeFormat)}}
+	wait, ok := retryAfter(h, now)
+	if !ok || wait != 90*time.Second {
+		t.Errorf("expected a 90s wait, got %s (ok=%v)", wait, ok)
+	}
+	h.Set("Retry-After", "soon")
+	if _, ok := retryAfter(h, now); ok {
+		t.Error("expected an unparseable Retry-After to be ignored")
+	}
+}
+
+// flakyHandler answers the first failures requests to each path with
+// status, then passes requests on to h.
+func flakyHandler(failures, status int, h http.Handler) (http.Handler, func(path string) int) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	flaky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.URL.Path]++
+		n := calls[r.URL.Path]
+		mu.Unlock()
+		if n <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+	return flaky, func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls[path]
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	var bodies []string
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	})
+	fast := RetryPolicy{InitialBackoff: time.Millisecond, MaxAttempts: 3}
+
+	h, calls := flakyHandler(2, http.StatusServiceUnavailable, ok)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	resp, err := retryClient(nil, fast).Get(srv.URL + "/get")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	assertEqualInt(t, "status", http.StatusOK, resp.StatusCode)
+	assertEqualInt(t, "GET attempts", 3, calls("/get"))
+
+	// Attempts run out.
+	h, calls = flakyHandler(5, http.StatusBadGateway, ok)
+	srv = httptest.NewServer(h)
+	defer srv.Close()
+	resp, err = retryClient(nil, fast).Get(srv.URL + "/get")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	assertEqualInt(t, "final status", http.StatusBadGateway, resp.StatusCode)
+	assertEqualInt(t, "attempts", 3, calls("/get"))
+
+	// Other statuses are returned as they are.
+	h, calls = flakyHandler(1, http.StatusNotFound, ok)
+	srv = httptest.NewServer(h)
+	defer srv.Close()
+	resp, _ = retryClient(nil, fast).Get(srv.URL + "/missing")
+	resp.Body.Close()
+	assertEqualInt(t, "404 attempts", 1, calls("/missing"))
+
+	// POSTs are only retried when the policy allows it, with the body
+	// sent again.
+	h, calls = flakyHandler(1, http.StatusServiceUnavailable, ok)
+	srv = httptest.NewServer(h)
+	defer srv.Close()
+	resp, _ = retryClient(nil, fast).Post(srv.URL+"/idempotent", "text/plain", strings.NewReader("payload"))
+	resp.Body.Close()
+	assertEqualInt(t, "idempotent-only POST attempts", 1, calls("/idempotent"))
+
+	fast.RetryNonIdempotent = true
+	bodies = nil
+	resp, err = retryClient(nil, fast).Post(srv.URL+"/any", "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	assertEqualInt(t, "POST attempts", 2, calls("/any"))
+	assertSliceEqual(t, "POST bodies", []string{"payload"}, bodies)
+}
+
+func TestRetryTransportConnectionReset(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	resp, err := retryClient(nil, RetryPolicy{InitialBackoff: time.Millisecond}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	assertEqualInt(t, "attempts", 2, attempts)
+}
+
+func TestRetryTransportCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	start := time.Now()
+	_, err := retryClient(nil, RetryPolicy{InitialBackoff: time.Hour, MaxBackoff: time.Hour}).Do(req)
+	if err == nil {
+		t.Fatal("expected an error once the context ended")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("backoff ignored the context for %s", elapsed)
+	}
+}
+
+func TestClientRetryPolicyBackends(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/team/repo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"full_name": "team/repo"})
+	})
+	mux.HandleFunc("GET /api/v4/projects/team%2Frepo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"path_with_namespace": "team/repo"})
+	})
+	mux.HandleFunc("GET /api/v1/version", giteaVersionHandler)
+	mux.HandleFunc("GET /api/v1/repos/team/repo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"full_name": "team/repo", "owner": map[string]any{"login": "team"}})
+	})
+	mux.HandleFunc("GET /api/v1/repos/team/repo/topics", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"topics": []string{}})
+	})
+	mux.HandleFunc("GET /api/v1/repos/team/repo/licenses", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]string{})
+	})
+	mux.HandleFunc("GET /2.0/repositories/team/repo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(bbRepository{FullName: "team/repo"})
+	})
+
+	tests := []struct {
+		name string
+		opt  func(url string) Option
+		repo func(url string) string
+		path string
+	}{
+		{"github", func(u string) Option { return WithGitHub(u, "") }, func(u string) string { return u + "/team/repo" }, "/api/v3/repos/team/repo"},
+		{"gitlab", func(u string) Option { return WithGitLab(u, "") }, func(u string) string { return u + "/team/repo" }, "/api/v4/projects/team/repo"},
+		{"gitea", func(u string) Option { return WithGitea(u, "") }, func(u string) string { return u + "/team/repo" }, "/api/v1/repos/team/repo"},
+		{"bitbucket", func(string) Option { return WithToken("bitbucket.org", "") }, func(string) string { return "https://bitbucket.org/team/repo" }, "/2.0/repositories/team/repo"},
+	}
+	origAPI := bitbucketAPI
+	defer func() { setBitbucketAPI(origAPI) }()
+
+	for _, tt := range tests {
+		h, calls := flakyHandler(2, http.StatusBadGateway, mux)
+		srv := httptest.NewServer(h)
+		setBitbucketAPI(srv.URL + "/2.0")
+
+		c := NewClient(
+			tt.opt(srv.URL),
+			WithInsecureHTTP("127.0.0.1"),
+			WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+		)
+		repo, err := c.FetchRepository(context.Background(), tt.repo(srv.URL))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		assertEqual(t, tt.name+" FullName", "team/repo", repo.FullName)
+		assertEqualInt(t, tt.name+" attempts", 3, calls(tt.path))
+		srv.Close()
+	}
+}
+
+func TestClientRetryResumesPagination(t *testing.T) {
+	page2Failures := 0
+	pages := map[string]int{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/version", giteaVersionHandler)
+	mux.HandleFunc("GET /api/v1/orgs/bigorg/repos", func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		pages[page]++
+		if page == "2" && page2Failures < 1 {
+			page2Failures++
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var repos []map[string]any
+		if n, _ := strconv.Atoi(page); n <= 3 {
+			for i := range 2 {
+				name := "bigorg/repo-" + page + "-" + strconv.Itoa(i)
+				repos = append(repos, map[string]any{"full_name": name, "owner": map[string]any{"login": "bigorg"}})
+			}
+		}
+		json.NewEncoder(w).Encode(repos)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := NewClient(
+		WithGitea(srv.URL, ""),
+		WithInsecureHTTP("127.0.0.1"),
+		WithRetryPolicy(RetryPolicy{InitialBackoff: time.Millisecond}),
+	)
+	repos, err := c.ListRepositories(context.Background(), srv.URL, "bigorg", ListOptions{PerPage: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqualInt(t, "repos", 6, len(repos))
+	assertEqualInt(t, "page 1 requests", 1, pages["1"])
+	assertEqualInt(t, "page 2 requests", 2, pages["2"])
+	assertEqualInt(t, "page 3 requests", 1, pages["3"])
+}
+
+func TestClientRetryPolicyDisablesGitLabRetries(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := NewClient(
+		WithGitLab(srv.URL, ""),
+		WithInsecureHTTP("127.0.0.1"),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}),
+	)
+	if _, err := c.FetchRepository(context.Background(), srv.URL+"/team/repo"); err == nil {
+		t.Fatal("expected an error")
+	}
+	assertEqualInt(t, "attempts", 2, attempts)
+}
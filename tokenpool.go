@@ -0,0 +1,201 @@
+package forges
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenQuota reports the rate limit state of one token in a pool set with
+// WithTokens, as last reported by the forge.
+type TokenQuota struct {
+	Token     string // masked to its last four characters
+	Limit     int    // -1 until the forge has reported it
+	Remaining int    // -1 until the forge has reported it
+	Reset     time.Time
+	Requests  int  // requests sent with the token
+	Exhausted bool // rate limited until Reset
+}
+
+// defaultRateLimitWait is how long a token is set aside after a rate limit
+// response that doesn't say when the limit resets.
+const defaultRateLimitWait = time.Minute
+
+// tokenPool spreads requests over several tokens for one domain. It sends
+// each request with the token that has the most quota left, taking tokens
+// with equal or unknown quota in turn, and sets a token aside when the
+// forge says it is rate limited.
+type tokenPool struct {
+	mu     sync.Mutex
+	tokens []string
+	quotas []TokenQuota
+	next   int
+}
+
+func newTokenPool(tokens []string) *tokenPool {
+	p := &tokenPool{tokens: tokens, quotas: make([]TokenQuota, len(tokens))}
+	for i, t := range tokens {
+		p.quotas[i] = TokenQuota{Token: maskToken(t), Limit: -1, Remaining: -1}
+	}
+	return p
+}
+
+func maskToken(token string) string {
+	if len(token) <= 4 {
+		return "****"
+	}
+	return "…" + token[len(token)-4:]
+}
+
+// pick chooses the token for the next request. When every token is rate
+// limited it returns the one that resets first.
+func (p *tokenPool) pick(now time.Time) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	best, bestScore := -1, -1
+	for n := range p.tokens {
+		j := (p.next + n) % len(p.tokens)
+		q := &p.quotas[j]
+		if q.Exhausted && now.Before(q.Reset) {
+			continue
+		}
+		q.Exhausted = false
+		score := q.Remaining
+		if score < 0 || (!q.Reset.IsZero() && !now.Before(q.Reset)) {
+			score = math.MaxInt // unknown, or the window has reset since
+		}
+		if score > bestScore {
+			best, bestScore = j, score
+		}
+	}
+	if best < 0 {
+		for j := range p.quotas {
+			if best < 0 || p.quotas[j].Reset.Before(p.quotas[best].Reset) {
+				best = j
+			}
+		}
+		return best
+	}
+	p.next = (best + 1) % len(p.tokens)
+	return best
+}
+
+// available reports whether any token is free of rate limits.
+func (p *tokenPool) available(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, q := range p.quotas {
+		if !q.Exhausted || !now.Before(q.Reset) {
+			return true
+		}
+	}
+	return false
+}
+
+// observe records the rate limit headers of a response sent with token i
+// and reports whether the response was a rate limit rejection.
+func (p *tokenPool) observe(i int, resp *http.Response, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	q := &p.quotas[i]
+	q.Requests++
+	h := resp.Header
+	if v, ok := rateLimitHeader(h, "Limit"); ok {
+		q.Limit = v
+	}
+	remaining, hasRemaining := rateLimitHeader(h, "Remaining")
+	if hasRemaining {
+		q.Remaining = remaining
+	}
+	if v, ok := rateLimitHeader(h, "Reset"); ok {
+		q.Reset = time.Unix(int64(v), 0)
+	}
+
+	limited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && ((hasRemaining && remaining == 0) || h.Get("Retry-After") != ""))
+	if !limited {
+		return false
+	}
+	q.Exhausted = true
+	q.Remaining = 0
+	if wait, ok := retryAfter(h, now); ok {
+		q.Reset = now.Add(wait)
+	} else if !q.Reset.After(now) {
+		q.Reset = now.Add(defaultRateLimitWait)
+	}
+	return true
+}
+
+// rateLimitHeader reads GitHub's X-RateLimit-<name> or GitLab's
+// RateLimit-<name> header.
+func rateLimitHeader(h http.Header, name string) (int, bool) {
+	v := h.Get("X-RateLimit-" + name)
+	if v == "" {
+		v = h.Get("RateLimit-" + name)
+	}
+	n, err := strconv.Atoi(v)
+	return n, err == nil
+}
+
+func (p *tokenPool) snapshot() []TokenQuota {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]TokenQuota(nil), p.quotas...)
+}
+
+// tokenPoolTransport authenticates each request to the forge's API host
+// with a token from the pool, retrying with another token when one is rate
+// limited.
+type tokenPoolTransport struct {
+	base  http.RoundTripper
+	host  string
+	forge ForgeType
+	pool  *tokenPool
+}
+
+func (t *tokenPoolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.ToLower(req.URL.Host) != t.host {
+		return t.base.RoundTrip(req)
+	}
+	replayable := req.Body == nil || req.GetBody != nil
+	for attempt := 0; ; attempt++ {
+		i := t.pool.pick(time.Now())
+		r := req.Clone(req.Context())
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+		setAuthHeader(r.Header, t.forge, t.pool.tokens[i])
+
+		resp, err := t.base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		if !t.pool.observe(i, resp, time.Now()) || !replayable || attempt+1 >= len(t.pool.tokens) {
+			return resp, nil
+		}
+		if !t.pool.available(time.Now()) {
+			// Every token is rate limited; let the caller see it.
+			return resp, nil
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+	}
+}
+
+// tokenPoolClient wraps hc to authenticate requests to apiHost with tokens
+// from pool.
+func tokenPoolClient(hc *http.Client, apiHost string, ft ForgeType, pool *tokenPool) *http.Client {
+	return wrapTransport(hc, func(base http.RoundTripper) http.RoundTripper {
+		return &tokenPoolTransport{base: base, host: apiHost, forge: ft, pool: pool}
+	})
+}
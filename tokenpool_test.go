@@ -0,0 +1,128 @@
+package forges
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenPoolPick(t *testing.T) {
+	now := time.Now()
+	p := newTokenPool([]string{"a", "b", "c"})
+
+	// Unknown quotas are taken in turn.
+	for _, want := range []int{0, 1, 2, 0} {
+		assertEqualInt(t, "round robin", want, p.pick(now))
+	}
+
+	// Then the token with the most quota left wins.
+	p.quotas[0].Remaining, p.quotas[0].Reset = 10, now.Add(time.Hour)
+	p.quotas[1].Remaining, p.quotas[1].Reset = 500, now.Add(time.Hour)
+	p.quotas[2].Remaining, p.quotas[2].Reset = 20, now.Add(time.Hour)
+	assertEqualInt(t, "most remaining", 1, p.pick(now))
+
+	// Rate limited tokens are skipped until they reset.
+	p.quotas[1].Exhausted = true
+	assertEqualInt(t, "skip exhausted", 2, p.pick(now))
+	p.quotas[0].Reset = now.Add(3 * time.Hour)
+	p.quotas[2].Reset = now.Add(3 * time.Hour)
+	assertEqualInt(t, "after reset", 1, p.pick(now.Add(2*time.Hour)))
+
+	// With every token limited, the one resetting first is used.
+	for i := range p.quotas {
+		p.quotas[i].Exhausted = true
+		p.quotas[i].Reset = now.Add(time.Duration(3-i) * time.Minute)
+	}
+	if p.available(now) {
+		t.Error("expected no token to be available")
+	}
+	assertEqualInt(t, "soonest reset", 2, p.pick(now))
+}
+
+func TestTokenPoolMask(t *testing.T) {
+	assertEqual(t, "long", "…wxyz", maskToken("ghp_abcdwxyz"))
+	assertEqual(t, "short", "****", maskToken("abc"))
+}
+
+func TestTokenPoolTransportScope(t *testing.T) {
+	var downloadAuth string
+	downloads := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloadAuth = r.Header.Get("Authorization")
+	}))
+	defer downloads.Close()
+
+	var apiAuth string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiAuth = r.Header.Get("Authorization")
+		http.Redirect(w, r, downloads.URL+"/archive.tar.gz", http.StatusFound)
+	}))
+	defer api.Close()
+
+	hc := tokenPoolClient(nil, strings.TrimPrefix(api.URL, "http://"), GitHub, newTokenPool([]string{"a"}))
+	resp, err := hc.Get(api.URL + "/archive")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	assertEqual(t, "API Authorization", "Bearer a", apiAuth)
+	assertEqual(t, "download Authorization", "", downloadAuth)
+}
+
+func TestClientTokenRotation(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	calls := map[string]int{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/octocat/hello-world", func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		calls[token]++
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Reset", reset)
+		if token == "token-one" {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"API rate limit exceeded"}`))
+			return
+		}
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		json.NewEncoder(w).Encode(map[string]any{"full_name": "octocat/hello-world"})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := NewClient(
+		WithGitHub(srv.URL, ""),
+		WithTokens(srv.URL, []string{"token-one", "token-two"}),
+		WithInsecureHTTP("127.0.0.1"),
+	)
+
+	for range 2 {
+		repo, err := c.FetchRepository(context.Background(), srv.URL+"/octocat/hello-world")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertEqual(t, "FullName", "octocat/hello-world", repo.FullName)
+	}
+	assertEqualInt(t, "rate limited token calls", 1, calls["token-one"])
+	assertEqualInt(t, "second token calls", 2, calls["token-two"])
+
+	quotas := c.TokenQuotas(srv.URL)
+	if len(quotas) != 2 {
+		t.Fatalf("expected 2 quotas, got %d", len(quotas))
+	}
+	assertEqualBool(t, "first exhausted", true, quotas[0].Exhausted)
+	assertEqualInt(t, "first remaining", 0, quotas[0].Remaining)
+	assertEqualBool(t, "second exhausted", false, quotas[1].Exhausted)
+	assertEqualInt(t, "second remaining", 4999, quotas[1].Remaining)
+	assertEqualInt(t, "second limit", 5000, quotas[1].Limit)
+	assertEqualInt(t, "second requests", 2, quotas[1].Requests)
+	assertEqual(t, "masked", "…-two", quotas[1].Token)
+
+	if c.TokenQuotas("github.com") != nil {
+		t.Error("expected no quotas for a domain without a pool")
+	}
+}
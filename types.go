@@ -17,32 +17,80 @@ const (
 // Repository holds normalized metadata about a source code repository,
 // independent of which forge hosts it.
 type Repository struct {
-	FullName            string    `json:"full_name"`
-	Owner               string    `json:"owner"`
-	Name                string    `json:"name"`
-	Description         string    `json:"description,omitempty"`
-	Homepage            string    `json:"homepage,omitempty"`
-	HTMLURL             string    `json:"html_url"`
-	Language            string    `json:"language,omitempty"`
-	License             string    `json:"license,omitempty"` // SPDX identifier
-	DefaultBranch       string    `json:"default_branch,omitempty"`
-	Fork                bool      `json:"fork"`
-	Archived            bool      `json:"archived"`
-	Private             bool      `json:"private"`
-	MirrorURL           string    `json:"mirror_url,omitempty"`
-	SourceName          string    `json:"source_name,omitempty"` // fork parent full name
-	Size                int       `json:"size"`
-	StargazersCount     int       `json:"stargazers_count"`
-	ForksCount          int       `json:"forks_count"`
-	OpenIssuesCount     int       `json:"open_issues_count"`
-	SubscribersCount    int       `json:"subscribers_count"`
-	HasIssues           bool      `json:"has_issues"`
-	PullRequestsEnabled bool      `json:"pull_requests_enabled"`
-	Topics              []string  `json:"topics,omitempty"`
-	LogoURL             string    `json:"logo_url,omitempty"`
-	CreatedAt           time.Time `json:"created_at"`
-	UpdatedAt           time.Time `json:"updated_at"`
-	PushedAt            time.Time `json:"pushed_at,omitzero"`
+	FullName              string    `json:"full_name"`
+	Owner                 string    `json:"owner"`
+	Name                  string    `json:"name"`
+	Description           string    `json:"description,omitempty"`
+	Homepage              string    `json:"homepage,omitempty"`
+	HTMLURL               string    `json:"html_url"`
+	Language              string    `json:"language,omitempty"`
+	License               string    `json:"license,omitempty"`            // SPDX identifier
+	LicenseRaw            string    `json:"license_raw,omitempty"`        // as reported by the forge
+	LicenseConfidence     float64   `json:"license_confidence,omitempty"` // set when License was detected from files
+	DefaultBranch         string    `json:"default_branch,omitempty"`
+	Fork                  bool      `json:"fork"`
+	Archived              bool      `json:"archived"`
+	Private               bool      `json:"private"`
+	MirrorURL             string    `json:"mirror_url,omitempty"`
+	SourceName            string    `json:"source_name,omitempty"` // fork parent full name
+	Size                  int       `json:"size"`
+	StargazersCount       int       `json:"stargazers_count"`
+	ForksCount            int       `json:"forks_count"`
+	OpenIssuesCount       int       `json:"open_issues_count"` // includes PRs on GitHub unless enriched
+	OpenPullRequestsCount int       `json:"open_pull_requests_count"`
+	SubscribersCount      int       `json:"subscribers_count"`
+	HasIssues             bool      `json:"has_issues"`
+	PullRequestsEnabled   bool      `json:"pull_requests_enabled"`
+	Topics                []string  `json:"topics,omitempty"`
+	LogoURL               string    `json:"logo_url,omitempty"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+	PushedAt              time.Time `json:"pushed_at,omitzero"`
+	FieldsPopulated       Fields    `json:"fields_populated"`
+}
+
+// Fields is a bitmap of Repository fields. Repository.FieldsPopulated uses it
+// to tell a zero value the forge reported apart from one it never sent.
+type Fields uint32
+
+const (
+	FieldDescription Fields = 1 << iota
+	FieldHomepage
+	FieldLanguage
+	FieldLicense
+	FieldDefaultBranch
+	FieldArchived
+	FieldMirrorURL
+	FieldSize
+	FieldStargazersCount
+	FieldForksCount
+	FieldOpenIssuesCount
+	FieldOpenPullRequestsCount
+	FieldSubscribersCount
+	FieldTopics
+	FieldPushedAt
+)
+
+// Has reports whether all fields in f are set.
+func (fs Fields) Has(f Fields) bool {
+	return fs&f == f
+}
+
+// FetchOptions selects optional enrichment for FetchRepositoryWithOptions.
+// Each option costs extra API calls on some forges.
+type FetchOptions struct {
+	// Extended fills fields the forge's repository endpoint leaves out:
+	// language, size and last push on GitLab, and watcher and fork counts
+	// on Bitbucket. GitLab projects have no homepage and its API doesn't
+	// count watchers, so Homepage and SubscribersCount stay unknown there
+	// and are left out of FieldsPopulated.
+	Extended bool
+	// DetectLicense classifies the LICENSE and COPYING files on the default
+	// branch when the forge reports no license. See DetectLicense.
+	DetectLicense bool
+	// IssueCounts sets exact OpenIssuesCount and OpenPullRequestsCount,
+	// as EnrichIssueCounts does.
+	IssueCounts bool
 }
 
 // ArchivedFilter controls how archived repositories are handled in list operations.
@@ -70,8 +118,120 @@ type ListOptions struct {
 	PerPage  int
 }
 
+// SearchSort controls the ordering of repository search results.
+type SearchSort int
+
+const (
+	SortBestMatch SearchSort = iota
+	SortStars
+	SortForks
+	SortUpdated
+)
+
+// SearchQuery describes a repository search. Each backend translates it into
+// its own search syntax; criteria a forge can't filter on server-side are
+// applied to each page of results, so pages may come back short.
+type SearchQuery struct {
+	Text     string
+	Topic    string
+	Language string
+	MinStars int
+	Archived ArchivedFilter
+	Forks    ForkFilter
+	Sort     SearchSort
+	Page     int // 1-based; 0 means the first page
+	PerPage  int
+}
+
+// Activity summarizes commit activity on a repository's default branch.
+//
+// Committers counts distinct commit authors since Since. An author is
+// identified by their forge account when the commit is linked to one and by
+// their email otherwise. GitLab's commit listing never links accounts, so
+// there every author is counted by email.
+type Activity struct {
+	Since        time.Time       `json:"since"`
+	LastCommitAt time.Time       `json:"last_commit_at,omitzero"`
+	TotalCommits int             `json:"total_commits"`
+	Committers   int             `json:"committers"`
+	Weeks        []WeeklyCommits `json:"weeks"`
+}
+
+// WeeklyCommits counts the commits in the week starting at Week, which is
+// always a Sunday at 00:00 UTC.
+type WeeklyCommits struct {
+	Week    time.Time `json:"week"`
+	Commits int       `json:"commits"`
+}
+
 // Tag represents a git tag.
 type Tag struct {
 	Name   string `json:"name"`
 	Commit string `json:"commit"` // SHA
 }
+
+// StateFilter controls which issues or pull requests are returned by state.
+type StateFilter int
+
+const (
+	StateOpen StateFilter = iota
+	StateClosed
+	StateAll
+)
+
+// String returns the state name used by the GitHub and Gitea APIs.
+func (s StateFilter) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateAll:
+		return "all"
+	default:
+		return "open"
+	}
+}
+
+// IssueListOptions configures ListIssues and ListPullRequests calls.
+type IssueListOptions struct {
+	State   StateFilter
+	PerPage int
+	Limit   int // stop after this many results; 0 means no limit
+}
+
+// Issue holds normalized metadata about an issue. Pull requests are never
+// returned as issues, even on forges whose APIs mix the two.
+type Issue struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	State     string    `json:"state"` // "open" or "closed"
+	Author    string    `json:"author,omitempty"`
+	HTMLURL   string    `json:"html_url"`
+	Labels    []string  `json:"labels,omitempty"`
+	Comments  int       `json:"comments"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ClosedAt  time.Time `json:"closed_at,omitzero"`
+}
+
+// PullRequest holds normalized metadata about a pull or merge request.
+type PullRequest struct {
+	Number     int       `json:"number"`
+	Title      string    `json:"title"`
+	State      string    `json:"state"` // "open", "closed" or "merged"
+	Author     string    `json:"author,omitempty"`
+	HTMLURL    string    `json:"html_url"`
+	Draft      bool      `json:"draft"`
+	HeadBranch string    `json:"head_branch,omitempty"`
+	BaseBranch string    `json:"base_branch,omitempty"`
+	Labels     []string  `json:"labels,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	ClosedAt   time.Time `json:"closed_at,omitzero"`
+	MergedAt   time.Time `json:"merged_at,omitzero"`
+}
+
+// IssueCounts holds exact open issue and pull request counts for a repository.
+type IssueCounts struct {
+	OpenIssues       int `json:"open_issues"`
+	OpenPullRequests int `json:"open_pull_requests"`
+}
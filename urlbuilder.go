@@ -0,0 +1,149 @@
+package forges
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ArchiveFormat selects the file type of a source archive download.
+type ArchiveFormat string
+
+const (
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+	ArchiveZip   ArchiveFormat = "zip"
+)
+
+// RefKind says what a ref names. Gitea and Forgejo put it in file links.
+type RefKind string
+
+const (
+	RefBranch RefKind = "branch"
+	RefTag    RefKind = "tag"
+	RefCommit RefKind = "commit"
+)
+
+// URLBuilder builds web, clone and download URLs for repositories on one
+// domain, following the path conventions of the forge software it runs.
+// It is the inverse of ParseRepoURL and ParseRepoLocation.
+type URLBuilder struct {
+	Type   ForgeType
+	Domain string // host, port and any path prefix, as registered
+	// BaseURL is the root of the web UI, for instances on plain HTTP or a
+	// custom port. It defaults to https://<Domain>.
+	BaseURL string
+}
+
+// NewURLBuilder returns a URLBuilder for a domain running the given forge
+// software. Forgejo is treated as Gitea.
+func NewURLBuilder(ft ForgeType, domain string) (*URLBuilder, error) {
+	switch ft {
+	case GitHub, GitLab, Gitea, Forgejo, Bitbucket:
+		return &URLBuilder{Type: ft, Domain: domain, BaseURL: "https://" + domain}, nil
+	default:
+		return nil, fmt.Errorf("unsupported forge type %q for %s", ft, domain)
+	}
+}
+
+// WebURL returns the repository's home page.
+func (b *URLBuilder) WebURL(owner, repo string) string {
+	base := b.BaseURL
+	if base == "" {
+		base = "https://" + b.Domain
+	}
+	return base + "/" + escapePath(owner) + "/" + url.PathEscape(repo)
+}
+
+// CloneURL returns the HTTPS clone URL.
+func (b *URLBuilder) CloneURL(owner, repo string) string {
+	return b.WebURL(owner, repo) + ".git"
+}
+
+// SSHCloneURL returns the scp-style SSH clone URL. SSH paths don't include
+// the web UI's path prefix and SSH doesn't use the web port, so only the
+// host of Domain is used.
+func (b *URLBuilder) SSHCloneURL(owner, repo string) string {
+	host, _, _ := strings.Cut(b.Domain, "/")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return "git@" + host + ":" + owner + "/" + repo + ".git"
+}
+
+// TagURL returns the page for a tag. Bitbucket has no tag page, so it links
+// to the source tree at the tag instead.
+func (b *URLBuilder) TagURL(owner, repo, tag string) string {
+	web := b.WebURL(owner, repo)
+	switch b.Type {
+	case GitLab:
+		return web + "/-/tags/" + escapePath(tag)
+	case Bitbucket:
+		return web + "/src/" + escapePath(tag)
+	default:
+		return web + "/releases/tag/" + escapePath(tag)
+	}
+}
+
+// CommitURL returns the page for a single commit.
+func (b *URLBuilder) CommitURL(owner, repo, sha string) string {
+	web := b.WebURL(owner, repo)
+	switch b.Type {
+	case GitLab:
+		return web + "/-/commit/" + sha
+	case Bitbucket:
+		return web + "/commits/" + sha
+	default:
+		return web + "/commit/" + sha
+	}
+}
+
+// FileURL returns the page showing a file at ref, which kind says is a
+// branch, tag or commit. Only Gitea and Forgejo link the kinds differently;
+// an empty kind links refs that look like commit SHAs as commits and
+// everything else as branches.
+func (b *URLBuilder) FileURL(owner, repo, ref string, kind RefKind, path string) string {
+	web := b.WebURL(owner, repo)
+	if kind == "" {
+		kind = RefBranch
+		if isCommitSHA(ref) {
+			kind = RefCommit
+		}
+	}
+	ref = escapePath(ref)
+	path = escapePath(strings.TrimPrefix(path, "/"))
+	switch b.Type {
+	case GitLab:
+		return web + "/-/blob/" + ref + "/" + path
+	case Gitea, Forgejo:
+		return web + "/src/" + string(kind) + "/" + ref + "/" + path
+	case Bitbucket:
+		return web + "/src/" + ref + "/" + path
+	default:
+		return web + "/blob/" + ref + "/" + path
+	}
+}
+
+// ArchiveURL returns the download URL for a source archive of ref.
+func (b *URLBuilder) ArchiveURL(owner, repo, ref string, format ArchiveFormat) string {
+	web := b.WebURL(owner, repo)
+	switch b.Type {
+	case GitLab:
+		// GitLab names the file after the project and ref.
+		name := url.PathEscape(repo + "-" + strings.ReplaceAll(ref, "/", "-"))
+		return web + "/-/archive/" + escapePath(ref) + "/" + name + "." + string(format)
+	case Bitbucket:
+		return web + "/get/" + escapePath(ref) + "." + string(format)
+	default:
+		return web + "/archive/" + escapePath(ref) + "." + string(format)
+	}
+}
+
+// escapePath escapes each segment of a slash-separated path.
+func escapePath(p string) string {
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		segs[i] = url.PathEscape(s)
+	}
+	return strings.Join(segs, "/")
+}
@@ -0,0 +1,107 @@
+package forges
+
+import "testing"
+
+func TestURLBuilder(t *testing.T) {
+	const sha = "4f2a9c1e0b7d"
+	tests := []struct {
+		ft                                          ForgeType
+		domain                                      string
+		web, clone, ssh, tag, commit, file, archive string
+	}{
+		{
+			ft: GitHub, domain: "github.com",
+			web:     "https://github.com/o/r",
+			clone:   "https://github.com/o/r.git",
+			ssh:     "git@github.com:o/r.git",
+			tag:     "https://github.com/o/r/releases/tag/v1.0.0",
+			commit:  "https://github.com/o/r/commit/" + sha,
+			file:    "https://github.com/o/r/blob/main/docs/read%20me.md",
+			archive: "https://github.com/o/r/archive/v1.0.0.tar.gz",
+		},
+		{
+			ft: GitLab, domain: "gitlab.com",
+			web:     "https://gitlab.com/o/r",
+			clone:   "https://gitlab.com/o/r.git",
+			ssh:     "git@gitlab.com:o/r.git",
+			tag:     "https://gitlab.com/o/r/-/tags/v1.0.0",
+			commit:  "https://gitlab.com/o/r/-/commit/" + sha,
+			file:    "https://gitlab.com/o/r/-/blob/main/docs/read%20me.md",
+			archive: "https://gitlab.com/o/r/-/archive/v1.0.0/r-v1.0.0.tar.gz",
+		},
+		{
+			ft: Gitea, domain: "codeberg.org",
+			web:     "https://codeberg.org/o/r",
+			clone:   "https://codeberg.org/o/r.git",
+			ssh:     "git@codeberg.org:o/r.git",
+			tag:     "https://codeberg.org/o/r/releases/tag/v1.0.0",
+			commit:  "https://codeberg.org/o/r/commit/" + sha,
+			file:    "https://codeberg.org/o/r/src/branch/main/docs/read%20me.md",
+			archive: "https://codeberg.org/o/r/archive/v1.0.0.tar.gz",
+		},
+		{
+			ft: Bitbucket, domain: "bitbucket.org",
+			web:     "https://bitbucket.org/o/r",
+			clone:   "https://bitbucket.org/o/r.git",
+			ssh:     "git@bitbucket.org:o/r.git",
+			tag:     "https://bitbucket.org/o/r/src/v1.0.0",
+			commit:  "https://bitbucket.org/o/r/commits/" + sha,
+			file:    "https://bitbucket.org/o/r/src/main/docs/read%20me.md",
+			archive: "https://bitbucket.org/o/r/get/v1.0.0.tar.gz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.ft), func(t *testing.T) {
+			b, err := NewURLBuilder(tt.ft, tt.domain)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertEqual(t, "WebURL", tt.web, b.WebURL("o", "r"))
+			assertEqual(t, "CloneURL", tt.clone, b.CloneURL("o", "r"))
+			assertEqual(t, "SSHCloneURL", tt.ssh, b.SSHCloneURL("o", "r"))
+			assertEqual(t, "TagURL", tt.tag, b.TagURL("o", "r", "v1.0.0"))
+			assertEqual(t, "CommitURL", tt.commit, b.CommitURL("o", "r", sha))
+			assertEqual(t, "FileURL", tt.file, b.FileURL("o", "r", "main", RefBranch, "docs/read me.md"))
+			assertEqual(t, "ArchiveURL", tt.archive, b.ArchiveURL("o", "r", "v1.0.0", ArchiveTarGz))
+		})
+	}
+}
+
+func TestURLBuilderRefs(t *testing.T) {
+	gitea, _ := NewURLBuilder(Forgejo, "codeberg.org")
+	assertEqual(t, "Gitea commit FileURL", "https://codeberg.org/o/r/src/commit/4f2a9c1e0b7d/go.mod", gitea.FileURL("o", "r", "4f2a9c1e0b7d", "", "go.mod"))
+	assertEqual(t, "Gitea tag FileURL", "https://codeberg.org/o/r/src/tag/v1.2.0/go.mod", gitea.FileURL("o", "r", "v1.2.0", RefTag, "go.mod"))
+	assertEqual(t, "Gitea escaped FileURL", "https://codeberg.org/o/r/src/branch/fix%23123%3F/go.mod", gitea.FileURL("o", "r", "fix#123?", RefBranch, "go.mod"))
+
+	bitbucket, _ := NewURLBuilder(Bitbucket, "bitbucket.org")
+	assertEqual(t, "Bitbucket branch ArchiveURL", "https://bitbucket.org/o/r/get/feature/x.zip", bitbucket.ArchiveURL("o", "r", "feature/x", ArchiveZip))
+
+	gitlab, _ := NewURLBuilder(GitLab, "gitlab.com")
+	assertEqual(t, "GitLab subgroup WebURL", "https://gitlab.com/g/sub/p", gitlab.WebURL("g/sub", "p"))
+	assertEqual(t, "GitLab branch ArchiveURL", "https://gitlab.com/o/r/-/archive/feature/x/r-feature-x.zip", gitlab.ArchiveURL("o", "r", "feature/x", ArchiveZip))
+
+	if _, err := NewURLBuilder(Unknown, "example.com"); err == nil {
+		t.Error("expected error for unknown forge type")
+	}
+}
+
+func TestClientURLBuilder(t *testing.T) {
+	c := NewClient(WithGitLab("gitlab.example.com", ""))
+
+	b, err := c.URLBuilder("gitlab.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "CommitURL", "https://gitlab.example.com/o/r/-/commit/abc1234", b.CommitURL("o", "r", "abc1234"))
+
+	b, err = c.URLBuilder("codeberg.org")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertEqual(t, "Type", string(Gitea), string(b.Type))
+
+	if _, err := c.URLBuilder("unregistered.example.com"); err == nil {
+		t.Error("expected error for unregistered domain")
+	}
+}